@@ -0,0 +1,332 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// the version of the bundle document written by export. import rejects bundles
+// of any other version.
+const BundleVersion = 1
+
+// policies for importing a parameter that already exists.
+const (
+	// fail the import before putting any parameter
+	ConflictFail = "fail"
+
+	// keep the existing parameter
+	ConflictSkip = "skip"
+
+	// overwrite the existing parameter with a new version
+	ConflictOverwrite = "overwrite"
+)
+
+// a Bundle holds every parameter below a path, for moving a hierarchy between
+// accounts or keeping it in backups.
+type Bundle struct {
+	Version int `json:"version" yaml:"version"`
+
+	// the path that was exported
+	Path string `json:"path" yaml:"path"`
+
+	Parameters []BundleParameter `json:"parameters" yaml:"parameters"`
+}
+
+// a BundleParameter is a single parameter of a Bundle. Its Name is relative to
+// the Path of the Bundle, so that it can be imported below another path.
+type BundleParameter struct {
+	Name        string            `json:"name" yaml:"name"`
+	Type        string            `json:"type" yaml:"type"`
+	KeyId       string            `json:"keyId,omitempty" yaml:"keyId,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Tier        string            `json:"tier,omitempty" yaml:"tier,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Value       string            `json:"value" yaml:"value"`
+}
+
+// Return json or yaml, from the explicit Format of the FileStore, or else from
+// the extension of its Path.
+func bundleFormatFor(store *FileStore) (string, error) {
+	format := store.Format
+	if len(format) == 0 {
+		format = strings.TrimPrefix(serialExt(store.Path), ".")
+	}
+
+	switch format {
+	case "json":
+		return "json", nil
+	case "yaml", "yml":
+		return "yaml", nil
+	default:
+		return "", errors.New("bundles must be json or yaml. filename " + store.Name)
+	}
+}
+
+// Return the parameter path of prefix without a trailing slash, keeping "/"
+// for the root path, so that a whole account can be exported.
+func bundlePath(prefix string) string {
+	if trimmed := strings.TrimSuffix(prefix, "/"); len(trimmed) > 0 {
+		return trimmed
+	}
+	return "/"
+}
+
+// Collect the parameters below paramPath into a Bundle. Parameters in
+// sub-paths are only collected when recursive.
+func buildBundle(ctx *CmdContext, paramPath string, recursive bool) (*Bundle, error) {
+	params, err := findAllParametersForPath(ctx, paramPath, recursive)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(params, func(i, j int) bool { return *params[i].Name < *params[j].Name })
+
+	bundle := Bundle{Version: BundleVersion, Path: paramPath, Parameters: make([]BundleParameter, 0, len(params))}
+	for _, param := range params {
+		name := *param.Name
+		entry := BundleParameter{
+			Name:  strings.TrimPrefix(strings.TrimPrefix(name, paramPath), "/"),
+			Type:  string(param.Type),
+			Value: *param.Value}
+
+		meta, err := ctx.Params.DescribeParameter(name)
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			if meta.Description != nil {
+				entry.Description = *meta.Description
+			}
+			entry.Tier = string(meta.Tier)
+			if param.Type == ssm.ParameterTypeSecureString && meta.KeyId != nil {
+				entry.KeyId = ctx.KmsMap.aliasFor(*meta.KeyId)
+			}
+		}
+
+		tags, err := ctx.Params.ListTags(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) > 0 {
+			entry.Tags = make(map[string]string, len(tags))
+			for _, tag := range tags {
+				entry.Tags[*tag.Key] = *tag.Value
+			}
+		}
+
+		bundle.Parameters = append(bundle.Parameters, entry)
+	}
+
+	return &bundle, nil
+}
+
+func writeBundle(w io.Writer, bundle *Bundle, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bundle)
+	}
+	return yaml.NewEncoder(w).Encode(bundle)
+}
+
+func readBundle(r io.Reader, format string) (*Bundle, error) {
+	bundle := Bundle{}
+	var err error
+	if format == "json" {
+		err = json.NewDecoder(r).Decode(&bundle)
+	} else {
+		err = yaml.NewDecoder(r).Decode(&bundle)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d. expected %d", bundle.Version, BundleVersion)
+	}
+	return &bundle, nil
+}
+
+// Export every parameter below prefix, recursively, to the bundle file named by
+// filename.
+func exportParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
+	store := ctx.Stores[filename]
+	format, err := bundleFormatFor(store)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := buildBundle(ctx, bundlePath(prefix), true)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	if err := writeBundle(&buf, bundle, format); err != nil {
+		return err
+	}
+
+	for _, param := range bundle.Parameters {
+		if param.Type == string(ssm.ParameterTypeSecureString) {
+			store.Secure = true
+		}
+	}
+	return store.write(buf.Bytes())
+}
+
+// Import every parameter of the bundle file named by filename below prefix.
+func importParamsPerFile(ctx *CmdContext, filename string, prefix string, onConflict string) error {
+	store := ctx.Stores[filename]
+	format, err := bundleFormatFor(store)
+	if err != nil {
+		return err
+	}
+
+	in := store.In
+	if !store.IsStream() {
+		file, err := os.Open(store.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	} else if in == nil {
+		return errors.New("no input stream for " + store.Name)
+	}
+
+	bundle, err := readBundle(in, format)
+	if err != nil {
+		return err
+	}
+
+	return putBundle(ctx, bundle, prefix, onConflict)
+}
+
+// Return the name of a bundle parameter below prefix. Returns an error if the
+// relative name is absolute, contains "..", or otherwise resolves outside
+// prefix, since a bundle may come from an untrusted backup.
+func bundleParamName(prefix string, relative string) (string, error) {
+	if len(relative) == 0 || strings.HasPrefix(relative, "/") {
+		return "", errors.New("bundle parameter names must be relative to the bundle path. name " + relative)
+	}
+	for _, segment := range strings.Split(relative, "/") {
+		if segment == ".." {
+			return "", errors.New("bundle parameter names must not contain '..'. name " + relative)
+		}
+	}
+
+	name := path.Join(prefix, relative)
+	if !strings.HasPrefix(name, strings.TrimSuffix(path.Clean(prefix), "/")+"/") {
+		return "", errors.New("bundle parameter name resolves outside of prefix " + prefix + ". name " + relative)
+	}
+	return name, nil
+}
+
+// Put every parameter of the bundle below prefix, handling parameters that
+// already exist according to the onConflict policy. Every name is validated,
+// and with ConflictFail also checked, before any parameter is put.
+func putBundle(ctx *CmdContext, bundle *Bundle, prefix string, onConflict string) error {
+	names := make([]string, len(bundle.Parameters))
+	for i, param := range bundle.Parameters {
+		name, err := bundleParamName(prefix, param.Name)
+		if err != nil {
+			return err
+		}
+		names[i] = name
+	}
+
+	existing := make(map[string]bool, 0)
+	found, _, err := ctx.Params.GetParameters(names)
+	if err != nil {
+		return err
+	}
+	for _, param := range found {
+		existing[*param.Name] = true
+	}
+
+	if onConflict == ConflictFail && len(existing) > 0 {
+		conflicts := make([]string, 0, len(existing))
+		for name := range existing {
+			conflicts = append(conflicts, name)
+		}
+		sort.Strings(conflicts)
+		return errors.New("parameters already exist: " + strings.Join(conflicts, ", "))
+	}
+
+	for i, param := range bundle.Parameters {
+		name := names[i]
+		if existing[name] && onConflict == ConflictSkip {
+			continue
+		}
+
+		value := param.Value
+		overwrite := existing[name]
+		input := ssm.PutParameterInput{
+			Name:      &name,
+			Value:     &value,
+			Type:      ssm.ParameterType(param.Type),
+			Tier:      ssm.ParameterTier(param.Tier),
+			Overwrite: &overwrite}
+		if len(param.KeyId) > 0 {
+			keyId := param.KeyId
+			input.KeyId = &keyId
+		}
+		if len(param.Description) > 0 {
+			description := param.Description
+			input.Description = &description
+		}
+
+		if err := ctx.Params.PutParameter(&input); err != nil {
+			return err
+		}
+
+		// tags may not be sent with an overwriting PutParameter, so they are
+		// always added separately.
+		if len(param.Tags) > 0 {
+			if err := ctx.Params.AddTags(name, bundleTags(param.Tags)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func bundleTags(tags map[string]string) []ssm.Tag {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ssmTags := make([]ssm.Tag, len(keys))
+	for i, key := range keys {
+		tagKey, tagValue := key, tags[key]
+		ssmTags[i] = ssm.Tag{Key: &tagKey, Value: &tagValue}
+	}
+	return ssmTags
+}
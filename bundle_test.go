@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"testing"
+)
+
+func TestExportImportBundle(t *testing.T) {
+	for _, filename := range []string{"bundle.json", "bundle.yaml"} {
+		ctx := newTestContext(t, []string{"/ep/conf"}, filename)
+		defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+		description := "database url"
+		putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+		putTestParam(t, ctx, "/ep/conf/ecs/db/password", "secret", ssm.ParameterTypeSecureString)
+		url, key, value := "/ep/conf/ecs/db/url", "team", "platform"
+		urlValue := "jdbc:h2:mem"
+		ctx.Params.PutParameter(&ssm.PutParameterInput{
+			Name: &url, Value: &urlValue, Type: ssm.ParameterTypeString, Description: &description,
+			Tier: ssm.ParameterTierAdvanced, Tags: []ssm.Tag{{Key: &key, Value: &value}}})
+
+		if err := exportParamsPerFile(ctx, filename, "/ep/conf"); err != nil {
+			t.Fatal(err)
+		}
+		assertFileMode(t, ctx.Stores[filename].Path, 0600)
+
+		if err := importParamsPerFile(ctx, filename, "/ep/conf", ConflictFail); err == nil {
+			t.Error("expected import to fail for existing parameters")
+		}
+		if err := importParamsPerFile(ctx, filename, "/ep/copy", ConflictFail); err != nil {
+			t.Fatal(err)
+		}
+
+		meta, _ := ctx.Params.DescribeParameter("/ep/copy/ecs/db/url")
+		if meta == nil || *meta.Description != description || meta.Tier != ssm.ParameterTierAdvanced {
+			t.Errorf("%s: expected the description and tier to be imported. actual: %v\n", filename, meta)
+		}
+		tags, _ := ctx.Params.ListTags("/ep/copy/ecs/db/url")
+		if len(tags) != 1 || *tags[0].Key != key || *tags[0].Value != value {
+			t.Errorf("%s: expected the tags to be imported. actual: %v\n", filename, tags)
+		}
+		meta, _ = ctx.Params.DescribeParameter("/ep/copy/ecs/db/password")
+		if meta == nil || meta.Type != ssm.ParameterTypeSecureString || *meta.KeyId != DefaultSecureStringKeyId {
+			t.Errorf("%s: expected a SecureString with the default key. actual: %v\n", filename, meta)
+		}
+
+		if err := importParamsPerFile(ctx, filename, "/ep/copy", ConflictSkip); err != nil {
+			t.Fatal(err)
+		}
+		if err := importParamsPerFile(ctx, filename, "/ep/copy", ConflictOverwrite); err != nil {
+			t.Fatal(err)
+		}
+		history, _ := ctx.Params.GetParameterHistory("/ep/copy/ecs/host")
+		if len(history) != 2 {
+			t.Errorf("%s: expected skip to keep and overwrite to add a version. actual: %d versions\n", filename, len(history))
+		}
+	}
+}
+
+func TestExportRootBundle(t *testing.T) {
+	ctx := newTestContext(t, []string{"/"}, "backup.json")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/other/port", "8080", ssm.ParameterTypeString)
+
+	if err := exportParamsPerFile(ctx, "backup.json", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := importParamsPerFile(ctx, "backup.json", "/restored", ConflictFail); err != nil {
+		t.Fatal(err)
+	}
+
+	params, invalid, _ := ctx.Params.GetParameters([]string{"/restored/ep/conf/ecs/host", "/restored/other/port"})
+	if len(params) != 2 {
+		t.Errorf("expected every parameter to be exported below the root path. missing: %v\n", invalid)
+	}
+}
+
+func TestPutBundleRejectsEscapingNames(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf/prod"})
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	for _, name := range []string{"../../../etc/x", "/etc/x", "ecs/../../x", ""} {
+		bundle := Bundle{Version: BundleVersion, Path: "/ep/conf", Parameters: []BundleParameter{
+			{Name: "ecs/host", Type: string(ssm.ParameterTypeString), Value: "localhost"},
+			{Name: name, Type: string(ssm.ParameterTypeString), Value: "escaped"}}}
+
+		if err := putBundle(ctx, &bundle, "/ep/conf/prod", ConflictOverwrite); err == nil {
+			t.Errorf("expected an error for bundle parameter name %q\n", name)
+		}
+	}
+
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/prod/ecs/host"); meta != nil {
+		t.Errorf("expected nothing to be put from a bundle with an invalid name\n")
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/etc/x"); meta != nil {
+		t.Errorf("expected nothing to be put outside of the prefix\n")
+	}
+}
@@ -18,15 +18,16 @@ package main
 
 import (
 	"errors"
-	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 type CmdContext struct {
 	Prefs  ParsedArgs
 	Stores map[string]*FileStore
-	Ssms   *ssm.SSM
+	Params ParameterStore
 	KmsMap KmsMap
 }
 
@@ -58,9 +59,26 @@ func doGet(ctx *CmdContext) {
 	}
 
 	for _, filename := range ctx.Prefs.Filenames {
-		if err := getParamsPerFile(ctx, filename); err != nil {
+		stale, err := getParamsPerFile(ctx, filename)
+		if err != nil {
 			log.Fatalf("Failed to get parameters for filename %s. reason: %s\n", filename, err)
 		}
+		if len(stale) > 0 {
+			action := "Kept"
+			if ctx.Prefs.Merge == MergeReplace {
+				action = "Removed"
+			}
+			fmt.Fprintf(os.Stderr, "%s stale local keys in %s, which were not found in SSM: %s\n",
+				action, filename, strings.Join(stale, ", "))
+		}
+	}
+}
+
+func doRender(ctx *CmdContext) {
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := renderParamsPerFile(ctx, filename); err != nil {
+			log.Fatalf("Failed to render template for filename %s. reason: %s\n", filename, err)
+		}
 	}
 }
 
@@ -77,13 +95,29 @@ func doPut(ctx *CmdContext) {
 	}
 }
 
+func doSync(ctx *CmdContext) {
+	if len(ctx.Prefs.Prefixes) != 1 {
+		log.Fatal("sync command requires exactly one -s/--starts-with argument.")
+	}
+
+	prefix := ctx.Prefs.Prefixes[0]
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := syncParamsPerFile(ctx, filename, prefix); err != nil {
+			log.Fatalf("Failed to sync parameters from filename %s to prefix %s. reason: %s\n", filename, prefix, err)
+		}
+	}
+}
+
 func doDelete(ctx *CmdContext) {
 	if len(ctx.Prefs.Prefixes) != 1 {
 		log.Fatal("delete command requires exactly one -s/--starts-with argument.")
 	}
 
+	prefix := ctx.Prefs.Prefixes[0]
 	for _, filename := range ctx.Prefs.Filenames {
-		deleteParamsPerFile(ctx, filename, ctx.Prefs.Prefixes[0])
+		if err := deleteParamsPerFile(ctx, filename, prefix); err != nil {
+			log.Fatalf("Failed to delete parameters from filename %s at prefix %s. reason: %s\n", filename, prefix, err)
+		}
 	}
 }
 
@@ -92,7 +126,128 @@ func doClear(ctx *CmdContext) {
 		log.Fatal("clear command requires exactly one -s/--starts-with argument.")
 	}
 
+	prefix := ctx.Prefs.Prefixes[0]
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := clearParamsPerFile(ctx, filename, prefix); err != nil {
+			log.Fatalf("Failed to clear parameters for filename %s at prefix %s. reason: %s\n", filename, prefix, err)
+		}
+	}
+}
+
+// the exit statuses of diff, which follow diff(1), so that differences can be
+// told apart from a failure to compare.
+const (
+	DiffExitDifferent = 1
+	DiffExitTrouble   = 2
+)
+
+// Log a failure like log.Fatalf, but exit with DiffExitTrouble for diff.
+func fatalf(ssmCmd string, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	if strings.ToLower(ssmCmd) == "diff" {
+		os.Exit(DiffExitTrouble)
+	}
+	os.Exit(1)
+}
+
+func doDiff(ctx *CmdContext) {
+	hasDiffs := false
+	for _, filename := range ctx.Prefs.Filenames {
+		fileDiffs, err := diffParamsPerFile(ctx, filename, os.Stdout)
+		if err != nil {
+			fatalf(ctx.Prefs.SsmCmd, "Failed to diff parameters for filename %s. reason: %s\n", filename, err)
+		}
+		hasDiffs = hasDiffs || fileDiffs
+	}
+
+	if hasDiffs {
+		os.Exit(DiffExitDifferent)
+	}
+}
+
+func doExec(ctx *CmdContext) {
+	if len(ctx.Prefs.ExecArgs) == 0 {
+		log.Fatal("exec command requires a command to run after --, like -- mycmd args")
+	}
+
+	paramEnv, err := buildExecEnv(ctx, ctx.Prefs.EnvKeys)
+	if err != nil {
+		log.Fatalf("Failed to get parameters for exec. reason: %s\n", err)
+	}
+
+	code, err := runChild(ctx.Prefs.ExecArgs, append(os.Environ(), paramEnv...))
+	if err != nil {
+		log.Fatalf("Failed to exec %s. reason: %s\n", ctx.Prefs.ExecArgs[0], err)
+	}
+	os.Exit(code)
+}
+
+func doExport(ctx *CmdContext) {
+	if len(ctx.Prefs.Prefixes) != 1 || len(ctx.Prefs.Filenames) != 1 {
+		log.Fatal("export command requires exactly one -s/--starts-with argument and exactly one -f/--filename argument.")
+	}
+
+	prefix, filename := ctx.Prefs.Prefixes[0], ctx.Prefs.Filenames[0]
+	if err := exportParamsPerFile(ctx, filename, prefix); err != nil {
+		log.Fatalf("Failed to export parameters from prefix %s to filename %s. reason: %s\n", prefix, filename, err)
+	}
+}
+
+func doImport(ctx *CmdContext) {
+	if len(ctx.Prefs.Prefixes) != 1 {
+		log.Fatal("import command requires exactly one -s/--starts-with argument.")
+	}
+
+	prefix := ctx.Prefs.Prefixes[0]
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := importParamsPerFile(ctx, filename, prefix, ctx.Prefs.OnConflict); err != nil {
+			log.Fatalf("Failed to import parameters from filename %s to prefix %s. reason: %s\n", filename, prefix, err)
+		}
+	}
+}
+
+func doCopy(ctx *CmdContext) {
+	if len(ctx.Prefs.Filenames) == 0 {
+		if err := copyParamsPerPath(ctx, ctx.Prefs.From, ctx.Prefs.To, true,
+			ctx.Prefs.ReencryptKey, ctx.Prefs.OnConflict); err != nil {
+			log.Fatalf("Failed to copy parameters from prefix %s to prefix %s. reason: %s\n", ctx.Prefs.From, ctx.Prefs.To, err)
+		}
+		return
+	}
+
 	for _, filename := range ctx.Prefs.Filenames {
-		clearParamsPerFile(ctx, filename, ctx.Prefs.Prefixes[0])
+		if err := copyParamsPerFile(ctx, filename); err != nil {
+			log.Fatalf("Failed to copy parameters for filename %s from prefix %s to prefix %s. reason: %s\n",
+				filename, ctx.Prefs.From, ctx.Prefs.To, err)
+		}
+	}
+}
+
+func doHistory(ctx *CmdContext) {
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := historyParamsPerFile(ctx, filename, os.Stdout); err != nil {
+			log.Fatalf("Failed to get parameter history for filename %s. reason: %s\n", filename, err)
+		}
+	}
+}
+
+func doRollback(ctx *CmdContext) {
+	if len(ctx.Prefs.Prefixes) != 1 {
+		log.Fatal("rollback command requires exactly one -s/--starts-with argument.")
+	}
+
+	prefix := ctx.Prefs.Prefixes[0]
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := rollbackParamsPerFile(ctx, filename, prefix, os.Stdout); err != nil {
+			log.Fatalf("Failed to roll back parameters for filename %s at prefix %s. reason: %s\n", filename, prefix, err)
+		}
+	}
+}
+
+func doLabel(ctx *CmdContext) {
+	for _, filename := range ctx.Prefs.Filenames {
+		if err := labelParamsPerFile(ctx, filename, os.Stdout); err != nil {
+			log.Fatalf("Failed to label parameters for filename %s. reason: %s\n", filename, err)
+		}
 	}
 }
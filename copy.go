@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"regexp"
+	"strings"
+)
+
+var kmsKeyIdPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Resolve a KMS key ID, key ARN, or key alias with or without the alias/
+// prefix, to the key ID where the alias is known.
+func targetKeyId(ctx *CmdContext, key string) string {
+	if strings.HasPrefix(key, "arn:") || kmsKeyIdPattern.MatchString(key) {
+		return key
+	}
+	return ctx.KmsMap.deref(key)
+}
+
+// Copy the parameters below fromPath to the same names below toPath, keeping
+// the type, KMS key, description, tier and tags of each. When reencryptKey is
+// not empty, SecureStrings are encrypted with that key instead.
+func copyParamsPerPath(ctx *CmdContext, fromPath string, toPath string, recursive bool,
+	reencryptKey string, onConflict string) error {
+	bundle, err := buildBundle(ctx, bundlePath(fromPath), recursive)
+	if err != nil {
+		return err
+	}
+
+	if len(reencryptKey) > 0 {
+		keyId := targetKeyId(ctx, reencryptKey)
+		for i, param := range bundle.Parameters {
+			if param.Type == string(ssm.ParameterTypeSecureString) {
+				bundle.Parameters[i].KeyId = keyId
+			}
+		}
+	}
+
+	return putBundle(ctx, bundle, toPath, onConflict)
+}
+
+// Copy the parameters for filename from the --from prefix to the --to prefix.
+func copyParamsPerFile(ctx *CmdContext, filename string) error {
+	return copyParamsPerPath(ctx,
+		buildParameterPath(ctx.Prefs.From, filename, ""),
+		buildParameterPath(ctx.Prefs.To, filename, ""),
+		isRecursiveFor(ctx, filename),
+		ctx.Prefs.ReencryptKey,
+		ctx.Prefs.OnConflict)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"testing"
+)
+
+func TestCopyParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+	ctx.Prefs.From = "/ep/conf/preprod"
+	ctx.Prefs.To = "/ep/conf/prod"
+	ctx.Prefs.OnConflict = ConflictSkip
+	ctx.KmsMap.aliasesToKeys["alias/prod-config"] = "prod-key-id"
+
+	putTestParam(t, ctx, "/ep/conf/preprod/ecs/host", "preprod.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/preprod/ecs/password", "secret", ssm.ParameterTypeSecureString)
+	putTestParam(t, ctx, "/ep/conf/preprod/ecs/deeper/ignored", "ignored", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/host", "prod.example.com", ssm.ParameterTypeString)
+
+	ctx.Prefs.ReencryptKey = "prod-config"
+	if err := copyParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+
+	params, _ := ctx.Params.GetParametersByPath("/ep/conf/prod/ecs", true)
+	dict := make(map[string]string, 0)
+	for _, param := range params {
+		dict[*param.Name] = *param.Value
+	}
+	assertDict(t, dict, map[string]string{
+		"/ep/conf/prod/ecs/host":     "prod.example.com",
+		"/ep/conf/prod/ecs/password": "secret"})
+
+	meta, _ := ctx.Params.DescribeParameter("/ep/conf/prod/ecs/password")
+	if meta == nil || *meta.KeyId != "prod-key-id" {
+		t.Errorf("expected the SecureString to be re-encrypted with the target key. actual: %v\n", meta)
+	}
+
+	if err := copyParamsPerPath(ctx, "/ep/conf/preprod", "/ep/conf/dr", true, "", ConflictFail); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ = ctx.Params.DescribeParameter("/ep/conf/dr/ecs/deeper/ignored")
+	if meta == nil {
+		t.Error("expected the whole subtree to be copied")
+	}
+	meta, _ = ctx.Params.DescribeParameter("/ep/conf/dr/ecs/password")
+	if meta == nil || *meta.KeyId != DefaultSecureStringKeyId {
+		t.Errorf("expected the SecureString to keep its key. actual: %v\n", meta)
+	}
+}
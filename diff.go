@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"sort"
+	"strings"
+)
+
+const MaskedValue = "********"
+
+// a DictDiff lists the keys that differ between a remote (SSM) dict and a
+// local (FileStore) dict, each in sorted order.
+type DictDiff struct {
+	// keys present only in the local dict
+	Added []string
+
+	// keys present only in the remote dict
+	Removed []string
+
+	// keys present in both dicts with different values
+	Changed []string
+}
+
+func (d DictDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func diffDicts(remote map[string]string, local map[string]string) DictDiff {
+	diff := DictDiff{}
+	for key, localValue := range local {
+		if remoteValue, ok := remote[key]; !ok {
+			diff.Added = append(diff.Added, key)
+		} else if remoteValue != localValue {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range remote {
+		if _, ok := local[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// Compare the local FileStore for filename against the dict merged from each
+// -s prefix, and write any differences to w. Returns true if there are
+// differences.
+func diffParamsPerFile(ctx *CmdContext, filename string, w io.Writer) (bool, error) {
+	remote := make(map[string]string, 0)
+	paramTypes := make(map[string]ssm.ParameterType, 0)
+	if err := mergeParamsPerFile(ctx, filename, &remote, paramTypes); err != nil {
+		return false, err
+	}
+
+	// sidecar keys are only retrieved from SSM when requested, so only compare
+	// them in that case.
+	store := ctx.Stores[filename]
+	local := make(map[string]string, len(store.Dict))
+	for key, value := range store.Dict {
+		if wantsKeyIdFor(ctx, filename) || !strings.HasSuffix(key, KeyIdSuffix) {
+			local[key] = value
+		}
+	}
+
+	diff := diffDicts(remote, local)
+	if diff.IsEmpty() {
+		return false, nil
+	}
+
+	display := func(key string, value string) string {
+		_, hasSidecar := store.Dict[key+KeyIdSuffix]
+		if !ctx.Prefs.ShowSecrets && (hasSidecar || paramTypes[key] == ssm.ParameterTypeSecureString) {
+			return MaskedValue
+		}
+		return value
+	}
+
+	var paramPaths []string
+	for _, prefix := range ctx.Prefs.Prefixes {
+		paramPaths = append(paramPaths, buildParameterPath(prefix, filename, ""))
+	}
+
+	fmt.Fprintf(w, "--- %s\n", strings.Join(paramPaths, ", "))
+	fmt.Fprintf(w, "+++ %s\n", store.Path)
+	for _, key := range diff.Removed {
+		fmt.Fprintf(w, "- %s=%s\n", key, display(key, remote[key]))
+	}
+	for _, key := range diff.Added {
+		fmt.Fprintf(w, "+ %s=%s\n", key, display(key, local[key]))
+	}
+	for _, key := range diff.Changed {
+		fmt.Fprintf(w, "~ %s: %s -> %s\n", key, display(key, remote[key]), display(key, local[key]))
+	}
+
+	return true, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf", "/ep/conf/prod"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/host", "prod.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/removed", "gone", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/password", "secret", ssm.ParameterTypeSecureString)
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["host"] = "prod.example.com"
+	store.Dict["password"] = "changed"
+	store.Dict["added"] = "new"
+	store.Dict["password"+KeyIdSuffix] = "alias/mykey"
+
+	out := bytes.Buffer{}
+	hasDiffs, err := diffParamsPerFile(ctx, "ecs.properties", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasDiffs {
+		t.Error("expected differences")
+	}
+
+	expected := []string{
+		"- removed=gone",
+		"+ added=new",
+		"~ password: " + MaskedValue + " -> " + MaskedValue}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected line %q in diff output:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "host") || strings.Contains(out.String(), KeyIdSuffix) {
+		t.Errorf("unchanged and sidecar keys should not be listed:\n%s", out.String())
+	}
+
+	ctx.Prefs.ShowSecrets = true
+	out.Reset()
+	diffParamsPerFile(ctx, "ecs.properties", &out)
+	if !strings.Contains(out.String(), "~ password: secret -> changed\n") {
+		t.Errorf("expected unmasked password change in diff output:\n%s", out.String())
+	}
+
+	delete(store.Dict, "added")
+	store.Dict["removed"] = "gone"
+	store.Dict["password"] = "secret"
+	if hasDiffs, _ := diffParamsPerFile(ctx, "ecs.properties", &out); hasDiffs {
+		t.Error("expected no differences")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the DotenvSerial reads and writes .env files, as consumed by dotenv
+// libraries. Values are written unquoted where possible, single-quoted when
+// they contain special characters, and double-quoted with escapes when they
+// contain newlines or single quotes.
+type DotenvSerial struct{}
+
+var dotenvBareValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+func (s DotenvSerial) Load(r io.Reader) (map[string]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDotenv(string(data))
+}
+
+func parseDotenv(data string) (map[string]string, error) {
+	dict := make(map[string]string)
+	rest := strings.Replace(data, "\r\n", "\n", -1)
+	for len(rest) > 0 {
+		var line string
+		if i := strings.IndexRune(rest, '\n'); i >= 0 {
+			line, rest = rest[:i], rest[i+1:]
+		} else {
+			line, rest = rest, ""
+		}
+
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		}
+
+		eq := strings.IndexRune(line, '=')
+		if eq <= 0 {
+			return nil, errors.New("expected KEY=value in .env line: " + line)
+		}
+
+		key := strings.TrimSpace(line[:eq])
+		value := strings.TrimLeft(line[eq+1:], " \t")
+
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+			// quoted values may continue onto the following lines
+			quoted := value
+			if len(rest) > 0 {
+				quoted += "\n" + rest
+			}
+			parsed, remainder, err := parseDotenvQuoted(quoted)
+			if err != nil {
+				return nil, errors.New(err.Error() + ". key " + key)
+			}
+			dict[key] = parsed
+
+			// discard anything after the closing quote on its line, such as a comment
+			if i := strings.IndexRune(remainder, '\n'); i >= 0 {
+				rest = remainder[i+1:]
+			} else {
+				rest = ""
+			}
+			continue
+		}
+
+		// strip an inline comment from an unquoted value
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		dict[key] = strings.TrimSpace(value)
+	}
+
+	return dict, nil
+}
+
+// parse a quoted value beginning at the opening quote, returning the unquoted
+// value and the input following the closing quote.
+func parseDotenvQuoted(input string) (string, string, error) {
+	quote := input[0]
+	buf := strings.Builder{}
+	for i := 1; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == quote:
+			return buf.String(), input[i+1:], nil
+		case c == '\\' && quote == '"' && i+1 < len(input):
+			i++
+			switch input[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case '"', '\\', '$':
+				buf.WriteByte(input[i])
+			default:
+				buf.WriteByte('\\')
+				buf.WriteByte(input[i])
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return "", "", errors.New("unterminated quoted value")
+}
+
+func quoteDotenv(value string) string {
+	if dotenvBareValue.MatchString(value) {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+		"$", "\\$",
+		"\n", "\\n",
+		"\r", "\\r")
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+func (s DotenvSerial) Save(w io.Writer, dict *map[string]string) error {
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	for _, key := range keys {
+		bw.WriteString(key + "=" + quoteDotenv((*dict)[key]) + "\n")
+	}
+
+	return bw.Flush()
+}
+
+func init() {
+	RegisterSerial(DotenvSerial{}, ".env")
+}
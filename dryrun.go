@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"strings"
+)
+
+// the DryRunParameterStore wraps another ParameterStore, passing reads through
+// and printing each write to Out instead of sending it. Writes are remembered,
+// so that a put following a dry-run delete behaves as it would for real.
+type DryRunParameterStore struct {
+	Store ParameterStore
+	Out   io.Writer
+
+	deleted map[string]bool
+	put     map[string]bool
+}
+
+func NewDryRunParameterStore(store ParameterStore, out io.Writer) *DryRunParameterStore {
+	return &DryRunParameterStore{
+		Store:   store,
+		Out:     out,
+		deleted: make(map[string]bool, 0),
+		put:     make(map[string]bool, 0)}
+}
+
+func (ps *DryRunParameterStore) exists(name string) (bool, error) {
+	if ps.put[name] {
+		return true, nil
+	}
+	if ps.deleted[name] {
+		return false, nil
+	}
+
+	meta, err := ps.Store.DescribeParameter(name)
+	return meta != nil, err
+}
+
+func (ps *DryRunParameterStore) GetParametersByPath(path string, recursive bool) ([]ssm.Parameter, error) {
+	return ps.Store.GetParametersByPath(path, recursive)
+}
+
+func (ps *DryRunParameterStore) GetParameters(names []string) ([]ssm.Parameter, []string, error) {
+	return ps.Store.GetParameters(names)
+}
+
+func (ps *DryRunParameterStore) PutParameter(input *ssm.PutParameterInput) error {
+	name := *input.Name
+	overwrite := input.Overwrite != nil && *input.Overwrite
+	exists, err := ps.exists(name)
+	if err != nil {
+		return err
+	}
+	if exists && !overwrite {
+		return errors.New("ParameterAlreadyExists: the parameter already exists. name " + name)
+	}
+
+	line := fmt.Sprintf("[dry-run] PutParameter Name=%s Type=%s", name, input.Type)
+	if input.KeyId != nil {
+		line += " KeyId=" + *input.KeyId
+	}
+	fmt.Fprintf(ps.Out, "%s Overwrite=%t\n", line, overwrite)
+
+	ps.put[name] = true
+	delete(ps.deleted, name)
+	return nil
+}
+
+func (ps *DryRunParameterStore) DeleteParameters(names []string) error {
+	for b := 0; b < len(names); b += ssmBatchSize {
+		end := b + ssmBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		fmt.Fprintf(ps.Out, "[dry-run] DeleteParameters Names=%s\n", strings.Join(names[b:end], ","))
+	}
+
+	for _, name := range names {
+		ps.deleted[name] = true
+		delete(ps.put, name)
+	}
+	return nil
+}
+
+func (ps *DryRunParameterStore) DescribeParameter(name string) (*ssm.ParameterMetadata, error) {
+	return ps.Store.DescribeParameter(name)
+}
+
+func (ps *DryRunParameterStore) GetParameterHistory(name string) ([]ssm.ParameterHistory, error) {
+	return ps.Store.GetParameterHistory(name)
+}
+
+func (ps *DryRunParameterStore) ListTags(name string) ([]ssm.Tag, error) {
+	return ps.Store.ListTags(name)
+}
+
+func (ps *DryRunParameterStore) AddTags(name string, tags []ssm.Tag) error {
+	exists, err := ps.exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("InvalidResourceId: name " + name)
+	}
+
+	pairs := make([]string, len(tags))
+	for i, tag := range tags {
+		pairs[i] = *tag.Key + "=" + *tag.Value
+	}
+	fmt.Fprintf(ps.Out, "[dry-run] AddTagsToResource Name=%s Tags=%s\n", name, strings.Join(pairs, ","))
+	return nil
+}
+
+func (ps *DryRunParameterStore) LabelParameterVersion(name string, version int64, labels []string) error {
+	exists, err := ps.exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("ParameterNotFound: name " + name)
+	}
+
+	line := fmt.Sprintf("[dry-run] LabelParameterVersion Name=%s", name)
+	if version != 0 {
+		line += fmt.Sprintf(" ParameterVersion=%d", version)
+	}
+	fmt.Fprintf(ps.Out, "%s Labels=%s\n", line, strings.Join(labels, ","))
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDryRunParameterStore(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/stale", "stale", ssm.ParameterTypeString)
+
+	mem := ctx.Params
+	out := bytes.Buffer{}
+	ctx.Params = NewDryRunParameterStore(mem, &out)
+	ctx.Prefs.ClearOnPut = true
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["host"] = "example.com"
+	store.Dict["password"] = "secret"
+	store.Dict["password"+KeyIdSuffix] = "alias/mykey"
+
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[dry-run] DeleteParameters Names=/ep/conf/ecs/host,/ep/conf/ecs/stale\n"
+	if !strings.HasPrefix(out.String(), expected) {
+		t.Errorf("expected delete request first. expected:\n%s\nactual:\n%s", expected, out.String())
+	}
+	if !bytes.Contains(out.Bytes(), []byte("[dry-run] PutParameter Name=/ep/conf/ecs/password Type=SecureString KeyId=alias/mykey Overwrite=false\n")) {
+		t.Errorf("expected secure put request. actual:\n%s", out.String())
+	}
+
+	if params, _ := mem.GetParametersByPath("/ep/conf/ecs", false); len(params) != 2 || *params[0].Value != "localhost" {
+		t.Errorf("dry run should not modify the underlying store. actual: %v\n", params)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// the signals received by ssmple that are forwarded to the child process of exec.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// Merge the parameters for each filename from each -s prefix, like get, and
+// convert them into NAME=value environment entries, using the keyMode to turn
+// keys into variable names. Parameters for later filenames override those for
+// earlier ones. Sidecar keys are not included.
+func buildExecEnv(ctx *CmdContext, keyMode string) ([]string, error) {
+	keysByName := make(map[string]string, 0)
+	valuesByName := make(map[string]string, 0)
+	for _, filename := range ctx.Prefs.Filenames {
+		dict := make(map[string]string, 0)
+		if err := mergeParamsPerFile(ctx, filename, &dict, nil); err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(dict))
+		for key := range dict {
+			if !isSidecarKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name, err := shellVarName(key, keyMode)
+			if err != nil {
+				return nil, err
+			}
+			if other, exists := keysByName[name]; exists && other != key {
+				return nil, errors.New("keys " + other + " and " + key + " map to the same environment variable " + name)
+			}
+			keysByName[name] = key
+			valuesByName[name] = dict[key]
+		}
+	}
+
+	names := make([]string, 0, len(valuesByName))
+	for name := range valuesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, len(names))
+	for i, name := range names {
+		env[i] = name + "=" + valuesByName[name]
+	}
+	return env, nil
+}
+
+// Run args as a child process with the given environment and the stdio of
+// ssmple, forwarding signals to it until it exits. Returns its exit code, or
+// 128 plus the signal number if it was killed by a signal.
+func runChild(args []string, env []string) (int, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				return 128 + int(status.Signal()), nil
+			}
+			return status.ExitStatus(), nil
+		}
+	}
+	return -1, err
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestBuildExecEnv(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf", "/ep/conf/prod"}, "app", "override")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/app/db.url", "jdbc:h2:mem", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/app/db.url", "jdbc:postgresql://db", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/app/hosts", "a,b", ssm.ParameterTypeStringList)
+	putTestParam(t, ctx, "/ep/conf/override/hosts", "c", ssm.ParameterTypeString)
+
+	env, err := buildExecEnv(ctx, ShellKeysUpper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"DB_URL=jdbc:postgresql://db", "HOSTS=c"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("unexpected exec env. expected: %v, actual: %v\n", expected, env)
+	}
+
+	if _, err := buildExecEnv(ctx, ShellKeysStrict); err == nil {
+		t.Error("expected an error for an invalid key in strict mode")
+	}
+}
+
+func TestRunChildExitCode(t *testing.T) {
+	code, err := runChild([]string{"sh", "-c", "test \"$GREETING\" = hello && exit 3"}, []string{"GREETING=hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("expected exit code 3. actual: %d\n", code)
+	}
+}
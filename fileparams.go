@@ -0,0 +1,284 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const FileBackendScheme = "file://"
+
+// extension appended to each parameter name to build its record filename. The
+// extension keeps a parameter named /a/b from colliding with the directory
+// holding the parameters below /a/b.
+const fileParamExt = ".json"
+
+// the FileParameterStore is a ParameterStore implementation that emulates the
+// SSM parameter hierarchy on the local filesystem, for offline development.
+// Each parameter is stored as a JSON record of its version history and tags, at
+// a path below Root matching the parameter name. SecureString values are NOT
+// encrypted.
+type FileParameterStore struct {
+	Root string
+}
+
+// Create a FileParameterStore from a backend URI like file:///some/dir.
+func NewFileParameterStore(uri string) (FileParameterStore, error) {
+	if !strings.HasPrefix(uri, FileBackendScheme) {
+		return FileParameterStore{}, errors.New("file backend must begin with " + FileBackendScheme + ". backend " + uri)
+	}
+
+	root, err := filepath.Abs(strings.TrimPrefix(uri, FileBackendScheme))
+	if err != nil {
+		return FileParameterStore{}, err
+	}
+
+	if _, err := requireDir(root, true); err != nil {
+		return FileParameterStore{}, err
+	}
+
+	return FileParameterStore{Root: root}, nil
+}
+
+// the JSON record of a single parameter.
+type fileParamRecord struct {
+	History []ssm.ParameterHistory
+	Tags    []ssm.Tag `json:",omitempty"`
+}
+
+// build the filename of the record for the named parameter. Names must begin
+// with '/' like SSM parameter paths, and may not resolve outside of Root.
+func (ps FileParameterStore) recordPath(name string) (string, error) {
+	if !strings.HasPrefix(name, "/") {
+		return "", errors.New("ValidationException: parameter name must begin with '/'. name " + name)
+	}
+	if name == "/" || path.Clean(name) != name {
+		return "", errors.New("ValidationException: invalid parameter name " + name)
+	}
+
+	file := filepath.Join(ps.Root, filepath.FromSlash(name)) + fileParamExt
+	rel, err := filepath.Rel(ps.Root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("ValidationException: parameter name resolves outside of the file backend. name " + name)
+	}
+	return file, nil
+}
+
+// list the names of all parameters stored below the given parameter path.
+func (ps FileParameterStore) walk(paramPath string) ([]string, error) {
+	var names []string
+	dir := filepath.Join(ps.Root, filepath.FromSlash(path.Clean(paramPath)))
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(file, fileParamExt) {
+			return nil
+		}
+
+		rel, relErr := filepath.Rel(ps.Root, file)
+		if relErr != nil {
+			return relErr
+		}
+		names = append(names, "/"+strings.TrimSuffix(filepath.ToSlash(rel), fileParamExt))
+		return nil
+	})
+
+	return names, err
+}
+
+// load the records for the named parameters into a MemParameterStore, so that
+// it can enforce the same rules as for the in-memory backend. Names without a
+// record are skipped.
+func (ps FileParameterStore) load(names ...string) (*MemParameterStore, error) {
+	mem := NewMemParameterStore()
+	for _, name := range names {
+		file, err := ps.recordPath(name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		record := fileParamRecord{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, errors.New("failed to read parameter record for name " + name + ". reason: " + err.Error())
+		}
+		mem.History[name] = record.History
+		if len(record.Tags) > 0 {
+			mem.Tags[name] = record.Tags
+		}
+	}
+
+	return mem, nil
+}
+
+// write the record for the named parameter from a MemParameterStore.
+func (ps FileParameterStore) save(mem *MemParameterStore, name string) error {
+	record := fileParamRecord{History: mem.History[name], Tags: mem.Tags[name]}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := ps.recordPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := requireDir(filepath.Dir(file), true); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, os.FileMode(int(0600)))
+}
+
+func (ps FileParameterStore) GetParametersByPath(path string, recursive bool) ([]ssm.Parameter, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("ValidationException: parameter path must begin with '/'. path " + path)
+	}
+
+	names, err := ps.walk(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := ps.load(names...)
+	if err != nil {
+		return nil, err
+	}
+
+	return mem.GetParametersByPath(path, recursive)
+}
+
+func (ps FileParameterStore) GetParameters(names []string) ([]ssm.Parameter, []string, error) {
+	// invalid names have no record, and are returned as invalid parameters.
+	recordNames := make([]string, 0, len(names))
+	for _, selectorName := range names {
+		name, _ := splitSelector(selectorName)
+		if _, err := ps.recordPath(name); err == nil {
+			recordNames = append(recordNames, name)
+		}
+	}
+
+	mem, err := ps.load(recordNames...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mem.GetParameters(names)
+}
+
+func (ps FileParameterStore) PutParameter(input *ssm.PutParameterInput) error {
+	if input.Name == nil {
+		return errors.New("ValidationException: parameter name must begin with '/'")
+	}
+
+	mem, err := ps.load(*input.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := mem.PutParameter(input); err != nil {
+		return err
+	}
+
+	return ps.save(mem, *input.Name)
+}
+
+func (ps FileParameterStore) DeleteParameters(names []string) error {
+	for _, name := range names {
+		file, err := ps.recordPath(name)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ps FileParameterStore) DescribeParameter(name string) (*ssm.ParameterMetadata, error) {
+	mem, err := ps.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return mem.DescribeParameter(name)
+}
+
+func (ps FileParameterStore) GetParameterHistory(name string) ([]ssm.ParameterHistory, error) {
+	mem, err := ps.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return mem.GetParameterHistory(name)
+}
+
+func (ps FileParameterStore) ListTags(name string) ([]ssm.Tag, error) {
+	mem, err := ps.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return mem.ListTags(name)
+}
+
+func (ps FileParameterStore) AddTags(name string, tags []ssm.Tag) error {
+	mem, err := ps.load(name)
+	if err != nil {
+		return err
+	}
+
+	if err := mem.AddTags(name, tags); err != nil {
+		return err
+	}
+
+	return ps.save(mem, name)
+}
+
+func (ps FileParameterStore) LabelParameterVersion(name string, version int64, labels []string) error {
+	mem, err := ps.load(name)
+	if err != nil {
+		return err
+	}
+
+	if err := mem.LabelParameterVersion(name, version, labels); err != nil {
+		return err
+	}
+
+	return ps.save(mem, name)
+}
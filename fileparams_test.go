@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileParameterStoreRoundTrip(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	fileParams, err := NewFileParameterStore(FileBackendScheme + filepath.Join(ctx.Prefs.ConfDir, "params"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Params = fileParams
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["host"] = "localhost"
+	store.Dict["password"] = "secret"
+	store.Dict["password"+KeyIdSuffix] = "alias/mykey"
+
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(fileParams.Root, "ep", "conf", "ecs", "password.json")); err != nil {
+		t.Errorf("expected a record file for /ep/conf/ecs/password. reason: %s\n", err)
+	}
+
+	ctx.Prefs.OverwritePut = true
+	store.Dict["host"] = "example.com"
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := ctx.Params.GetParameterHistory("/ep/conf/ecs/host")
+	if len(history) != 2 || *history[1].Version != 2 {
+		t.Errorf("expected two versions of /ep/conf/ecs/host. actual: %v\n", history)
+	}
+
+	ctx.Prefs.GetKeyId = true
+	store.Dict = make(map[string]string)
+	if _, err := getParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertDict(t, store.Dict, map[string]string{
+		"host":                   "example.com",
+		"password":               "secret",
+		"password" + KeyIdSuffix: "alias/mykey"})
+}
+
+func TestFileParameterStoreRejectsNamesOutsideRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileParams, err := NewFileParameterStore(FileBackendScheme + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"relative", "../outside", "/", "/ep/../conf", "/ep/conf/"} {
+		if _, err := fileParams.DescribeParameter(name); err == nil {
+			t.Errorf("expected DescribeParameter to reject name %s\n", name)
+		}
+		if err := fileParams.DeleteParameters([]string{name}); err == nil {
+			t.Errorf("expected DeleteParameters to reject name %s\n", name)
+		}
+		if err := fileParams.LabelParameterVersion(name, 1, []string{"release"}); err == nil {
+			t.Errorf("expected LabelParameterVersion to reject name %s\n", name)
+		}
+	}
+
+	params, invalid, err := fileParams.GetParameters([]string{"../outside"})
+	if err != nil || len(params) != 0 || len(invalid) != 1 {
+		t.Errorf("expected GetParameters to return invalid names as invalid. actual: %v %v %v\n", params, invalid, err)
+	}
+}
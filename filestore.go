@@ -17,27 +17,108 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// the filename argument selecting stdin and stdout instead of a file.
+const StreamFilename = "-"
+
 // a FileStore struct accumulates the parameter state for each file.
 // the FileStore is seri/deseried from the Path using a Serial object
 // matched to the extension of the path.
 type FileStore struct {
 	Path string
 	Dict map[string]string
+
+	// the filename, relative to the confDir, from which parameter paths are built
+	Name string
+
+	// an explicit format name overriding the extension of the Path, like yaml
+	Format string
+
+	// the streams of a FileStore created by NewStreamFileStore. A nil In is
+	// loaded as empty.
+	In  io.Reader
+	Out io.Writer
+
+	// how the file is written by Save
+	Options WriteOptions
+
+	// the format-specific options passed to the Serial
+	SerialOptions SerialOptions
+
+	// true if the Dict holds SecureString values, which restricts the default
+	// file mode to 0600
+	Secure bool
+}
+
+// options for writing a file, set by the --file-mode, --file-owner,
+// --file-group and --backup flags.
+type WriteOptions struct {
+	// the file mode, or 0 for the default
+	Mode os.FileMode
+
+	// the numeric owner and group ids, or -1 to leave them unchanged
+	Uid int
+	Gid int
+
+	// true to keep the previous version of the file with a .bak extension
+	Backup bool
+}
+
+// the default WriteOptions, which leave the owner and group unchanged.
+func NewWriteOptions() WriteOptions {
+	return WriteOptions{Uid: -1, Gid: -1}
+}
+
+// the suffix appended to the path of the previous version of a file when
+// WriteOptions.Backup is set.
+const BackupSuffix = ".bak"
+
+// Return the Serial for the explicit Format, or else for the extension of the
+// Path.
+func (fs *FileStore) serial() Serial {
+	serial, ok := GetSerialForFormat(fs.Format)
+	if !ok {
+		serial = GetSerialFor(fs.Path)
+	}
+	if configurable, ok := serial.(ConfigurableSerial); ok {
+		return configurable.Configure(fs.Name, fs.SerialOptions)
+	}
+	return serial
+}
+
+// Return true if the FileStore reads from In and writes to Out instead of a file.
+func (fs *FileStore) IsStream() bool {
+	return fs.Path == StreamFilename
 }
 
 // the *FileStore.Load() function encapsulates the input/output of the
 // associated Serial
 func (fs *FileStore) Load() error {
-	serial := GetSerialFor(fs.Path)
-	dict, err := serial.Load(fs.Path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+	r := fs.In
+	if !fs.IsStream() {
+		file, err := os.Open(fs.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
 		}
+		defer file.Close()
+		r = file
+	} else if r == nil {
+		return nil
+	}
+
+	dict, err := fs.serial().Load(r)
+	if err != nil {
 		return err
 	} else {
 		fs.Dict = dict
@@ -45,9 +126,157 @@ func (fs *FileStore) Load() error {
 	}
 }
 
+// Serialize the Dict in full before writing it, so that a Serial error does
+// not leave a truncated file.
 func (fs *FileStore) Save() error {
-	serial := GetSerialFor(fs.Path)
-	return serial.Save(fs.Path, &fs.Dict)
+	serial := fs.serial()
+	buf := bytes.Buffer{}
+	if layout, ok := serial.(LayoutSerial); ok && !fs.IsStream() {
+		previous, err := os.Open(fs.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			defer previous.Close()
+			err = layout.SaveOver(&buf, previous, &fs.Dict)
+		} else {
+			err = layout.SaveOver(&buf, nil, &fs.Dict)
+		}
+		if err != nil {
+			return err
+		}
+	} else if err := serial.Save(&buf, &fs.Dict); err != nil {
+		return err
+	}
+
+	return fs.write(buf.Bytes())
+}
+
+// Write data to Out for a stream, or else atomically to the Path.
+func (fs *FileStore) write(data []byte) error {
+	if fs.IsStream() {
+		if fs.Out == nil {
+			return errors.New("no output stream for " + fs.Name)
+		}
+		_, err := fs.Out.Write(data)
+		return err
+	}
+
+	return writeFileAtomic(fs.Path, data, fs.writeOptions())
+}
+
+// Return the Options for writing the file, restricting the default mode to 0600
+// when the Dict holds SecureString values.
+func (fs *FileStore) writeOptions() WriteOptions {
+	options := fs.Options
+	if options.Mode == 0 && fs.hasSecureString() {
+		options.Mode = 0600
+	}
+	return options
+}
+
+// Return true if the Dict holds SecureString values, either as recorded on get
+// or as marked by _SecureStringKeyId sidecars.
+func (fs *FileStore) hasSecureString() bool {
+	if fs.Secure {
+		return true
+	}
+	for key := range fs.Dict {
+		if strings.HasSuffix(key, KeyIdSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write data to a temp file in the same directory as path, fsync it, and
+// rename it over path, so that readers never see a partially written file.
+// When options.Mode is 0, the mode of the existing file is kept, or else 0644
+// is used.
+func writeFileAtomic(path string, data []byte, options WriteOptions) error {
+	mode := options.Mode
+	previous, statErr := os.Stat(path)
+	if statErr != nil && !os.IsNotExist(statErr) {
+		return statErr
+	}
+	if mode == 0 {
+		mode = 0644
+		if statErr == nil {
+			mode = previous.Mode().Perm()
+		}
+	}
+
+	if options.Backup && statErr == nil {
+		backup, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		backupOptions := options
+		backupOptions.Mode = previous.Mode().Perm()
+		backupOptions.Backup = false
+		if err := writeFileAtomic(path+BackupSuffix, backup, backupOptions); err != nil {
+			return err
+		}
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeTempFile(tmp, data, mode, options); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory so that the rename itself is durable. Not every
+	// platform supports this, so failures are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func writeTempFile(tmp *os.File, data []byte, mode os.FileMode, options WriteOptions) error {
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if options.Uid >= 0 || options.Gid >= 0 {
+		if err := tmp.Chown(options.Uid, options.Gid); err != nil {
+			return err
+		}
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	return tmp.Sync()
+}
+
+// Return true if the Serial for the path reads and writes nested documents,
+// whose keys map onto SSM parameter sub-paths.
+func (fs *FileStore) IsNested() bool {
+	if nested, ok := fs.serial().(NestedSerial); ok {
+		return nested.IsNested()
+	}
+	return false
+}
+
+// Return true if the Serial for the path needs the _SecureStringKeyId sidecar
+// of each SecureString parameter to be retrieved on get.
+func (fs *FileStore) NeedsKeyId() bool {
+	if secure, ok := fs.serial().(SecureSerial); ok {
+		return secure.NeedsKeyId()
+	}
+	return false
 }
 
 func NewFileStore(confDir string, filename string) FileStore {
@@ -55,8 +284,23 @@ func NewFileStore(confDir string, filename string) FileStore {
 	dict := make(map[string]string, 0)
 
 	store := FileStore{
-		Path: path,
-		Dict: dict}
+		Path:    path,
+		Dict:    dict,
+		Name:    filename,
+		Options: NewWriteOptions()}
 
 	return store
 }
+
+// Create a FileStore that reads from in and writes to out in the given format,
+// building parameter paths from name as if it were a filename.
+func NewStreamFileStore(name string, format string, in io.Reader, out io.Writer) FileStore {
+	return FileStore{
+		Path:    StreamFilename,
+		Dict:    make(map[string]string, 0),
+		Name:    name,
+		Format:  format,
+		In:      in,
+		Out:     out,
+		Options: NewWriteOptions()}
+}
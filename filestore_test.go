@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveSecureStringMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := NewFileStore(dir, "app.properties")
+	fs.Dict = map[string]string{"host": "localhost"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	assertFileMode(t, fs.Path, 0644)
+
+	fs.Dict["password"] = "secret"
+	fs.Dict["password"+KeyIdSuffix] = ""
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	assertFileMode(t, fs.Path, 0600)
+
+	fs.Options.Mode = 0640
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	assertFileMode(t, fs.Path, 0640)
+
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no temp files to be left behind. actual: %d entries\n", len(entries))
+	}
+}
+
+func TestSaveBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.env")
+	ioutil.WriteFile(path, []byte("OLD=value\n"), 0640)
+
+	fs := NewFileStore(dir, "app.env")
+	fs.Options.Backup = true
+	fs.Dict = map[string]string{"NEW": "value"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadFile(path + BackupSuffix); string(data) != "OLD=value\n" {
+		t.Errorf("expected the previous version in the backup. actual: %s\n", string(data))
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "NEW=value\n" {
+		t.Errorf("expected the new version in the file. actual: %s\n", string(data))
+	}
+	assertFileMode(t, path, 0640)
+	assertFileMode(t, path+BackupSuffix, 0640)
+}
+
+func assertFileMode(t *testing.T, path string, expected os.FileMode) {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != expected {
+		t.Errorf("unexpected mode for %s. expected: %o, actual: %o\n", path, expected, info.Mode().Perm())
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// List the names of the parameters for the keys of filename below prefix, in
+// sorted order, including keys whose parameters have been deleted.
+func findParamNamesPerFile(ctx *CmdContext, filename string, prefix string) []string {
+	store := ctx.Stores[filename]
+	names := make([]string, 0, len(store.Dict))
+	for key := range store.Dict {
+		if !isSidecarKey(key) {
+			names = append(names, buildParameterPathForKey(ctx, prefix, filename, key))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the history of the parameter called name, or nil if it does not
+// exist.
+func findParamHistory(ctx *CmdContext, name string) ([]ssm.ParameterHistory, error) {
+	meta, err := ctx.Params.DescribeParameter(name)
+	if err != nil || meta == nil {
+		return nil, err
+	}
+	return ctx.Params.GetParameterHistory(name)
+}
+
+// Write every version of each parameter for filename, from each -s prefix, to
+// w. SecureString values are masked unless ShowSecrets.
+func historyParamsPerFile(ctx *CmdContext, filename string, w io.Writer) error {
+	for _, prefix := range ctx.Prefs.Prefixes {
+		for _, name := range findParamNamesPerFile(ctx, filename, prefix) {
+			history, err := findParamHistory(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "%s\n", name)
+			if len(history) == 0 {
+				fmt.Fprintf(w, "  no versions\n")
+			}
+			for _, version := range history {
+				value := MaskedValue
+				if ctx.Prefs.ShowSecrets || version.Type != ssm.ParameterTypeSecureString {
+					value = unescapeValueAfterGet(*version.Value)
+				}
+				fmt.Fprintf(w, "  v%d  %s  %s  [%s]  %s\n", *version.Version, formatHistoryDate(version.LastModifiedDate),
+					formatHistoryUser(version.LastModifiedUser), strings.Join(version.Labels, ","), value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatHistoryDate(date *time.Time) string {
+	if date == nil {
+		return "-"
+	}
+	return date.UTC().Format(time.RFC3339)
+}
+
+func formatHistoryUser(user *string) string {
+	if user == nil || len(*user) == 0 {
+		return "-"
+	}
+	return *user
+}
+
+// Select the version of a parameter to roll back to: the version numbered
+// toVersion when it is positive, or else the latest version modified at or
+// before toTime. Returns nil if there is no such version.
+func selectRollbackVersion(history []ssm.ParameterHistory, toVersion int64, toTime time.Time) *ssm.ParameterHistory {
+	var selected *ssm.ParameterHistory
+	for i := range history {
+		version := &history[i]
+		if toVersion > 0 {
+			if *version.Version == toVersion {
+				return version
+			}
+		} else if version.LastModifiedDate != nil && !version.LastModifiedDate.After(toTime) {
+			if selected == nil || *version.Version > *selected.Version {
+				selected = version
+			}
+		}
+	}
+	return selected
+}
+
+// Put the selected earlier value of each parameter for filename below prefix
+// back as a new version, writing what was done to w. Parameters without the
+// selected version, or already at it, are left unchanged.
+func rollbackParamsPerFile(ctx *CmdContext, filename string, prefix string, w io.Writer) error {
+	if ctx.Prefs.ToVersion <= 0 && ctx.Prefs.ToTime.IsZero() {
+		return errors.New("rollback requires a version or a time to roll back to")
+	}
+
+	for _, name := range findParamNamesPerFile(ctx, filename, prefix) {
+		history, err := findParamHistory(ctx, name)
+		if err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			fmt.Fprintf(w, "skipped %s: no versions\n", name)
+			continue
+		}
+
+		latest := history[len(history)-1]
+		target := selectRollbackVersion(history, ctx.Prefs.ToVersion, ctx.Prefs.ToTime)
+		if target == nil {
+			fmt.Fprintf(w, "skipped %s: no version to roll back to\n", name)
+			continue
+		}
+		if *target.Version == *latest.Version {
+			continue
+		}
+
+		nameCopy := name
+		overwrite := true
+		input := ssm.PutParameterInput{
+			Name:           &nameCopy,
+			Value:          target.Value,
+			Type:           target.Type,
+			Description:    target.Description,
+			Tier:           target.Tier,
+			AllowedPattern: target.AllowedPattern,
+			Overwrite:      &overwrite}
+		if target.Type == ssm.ParameterTypeSecureString {
+			input.KeyId = target.KeyId
+		}
+
+		if err := ctx.Params.PutParameter(&input); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "rolled back %s from v%d to the value of v%d\n", name, *latest.Version, *target.Version)
+	}
+
+	return nil
+}
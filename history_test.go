@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"os"
+	"strings"
+	"testing"
+)
+
+func overwriteTestParam(t *testing.T, ctx *CmdContext, name string, value string) {
+	overwrite := true
+	input := ssm.PutParameterInput{Name: &name, Value: &value, Overwrite: &overwrite}
+	if err := ctx.Params.PutParameter(&input); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryAndRollback(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "one.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/password", "first", ssm.ParameterTypeSecureString)
+	overwriteTestParam(t, ctx, "/ep/conf/ecs/host", "two.example.com")
+	overwriteTestParam(t, ctx, "/ep/conf/ecs/password", "second")
+	putTestParam(t, ctx, "/ep/conf/ecs/port", "8080", ssm.ParameterTypeString)
+	overwriteTestParam(t, ctx, "/ep/conf/ecs/port", "8081")
+	overwriteTestParam(t, ctx, "/ep/conf/ecs/port", "8082")
+
+	store := ctx.Stores["ecs.properties"]
+	for _, key := range []string{"host", "password", "port", "removed"} {
+		store.Dict[key] = ""
+	}
+	store.Dict["password"+KeyIdSuffix] = ""
+
+	out := bytes.Buffer{}
+	if err := historyParamsPerFile(ctx, "ecs.properties", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "v2") || !strings.Contains(out.String(), "two.example.com") {
+		t.Errorf("expected every version in the history. actual:\n%s\n", out.String())
+	}
+	if strings.Contains(out.String(), "second") || !strings.Contains(out.String(), MaskedValue) {
+		t.Errorf("expected SecureString values to be masked. actual:\n%s\n", out.String())
+	}
+	if !strings.Contains(out.String(), "/ep/conf/ecs/removed\n  no versions\n") {
+		t.Errorf("expected keys without a parameter in the history. actual:\n%s\n", out.String())
+	}
+
+	ctx.Prefs.ToVersion = 1
+	out.Reset()
+	if err := rollbackParamsPerFile(ctx, "ecs.properties", "/ep/conf", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	params, _, _ := ctx.Params.GetParameters([]string{"/ep/conf/ecs/host", "/ep/conf/ecs/password", "/ep/conf/ecs/port"})
+	dict := make(map[string]string, 0)
+	for _, param := range params {
+		dict[*param.Name] = *param.Value
+	}
+	assertDict(t, dict, map[string]string{
+		"/ep/conf/ecs/host":     "one.example.com",
+		"/ep/conf/ecs/password": "first",
+		"/ep/conf/ecs/port":     "8080"})
+
+	history, _ := ctx.Params.GetParameterHistory("/ep/conf/ecs/host")
+	if len(history) != 3 {
+		t.Errorf("expected the rollback to add a version. actual: %d versions\n", len(history))
+	}
+	meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/password")
+	if meta.Type != ssm.ParameterTypeSecureString {
+		t.Errorf("expected the rollback to keep the SecureString type. actual: %s\n", meta.Type)
+	}
+}
+
+func TestRollbackKeepsTierAndAllowedPattern(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	name, value, pattern := "/ep/conf/ecs/port", "8080", "^[0-9]+$"
+	input := ssm.PutParameterInput{Name: &name, Value: &value, Type: ssm.ParameterTypeString,
+		Tier: ssm.ParameterTierAdvanced, AllowedPattern: &pattern}
+	if err := ctx.Params.PutParameter(&input); err != nil {
+		t.Fatal(err)
+	}
+	overwriteTestParam(t, ctx, name, "8081")
+	ctx.Stores["ecs.properties"].Dict["port"] = "8081"
+
+	ctx.Prefs.ToVersion = 1
+	if err := rollbackParamsPerFile(ctx, "ecs.properties", "/ep/conf", &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _ := ctx.Params.DescribeParameter(name)
+	if meta == nil || *meta.Version != 3 {
+		t.Fatalf("expected the rollback to add a version. actual: %v\n", meta)
+	}
+	if meta.Tier != ssm.ParameterTierAdvanced || meta.AllowedPattern == nil || *meta.AllowedPattern != pattern {
+		t.Errorf("expected the rollback to keep the tier and allowed pattern of v1. actual: %s %v\n", meta.Tier, meta.AllowedPattern)
+	}
+}
+
+func TestSelectRollbackVersionByTime(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "one.example.com", ssm.ParameterTypeString)
+	history, _ := ctx.Params.GetParameterHistory("/ep/conf/ecs/host")
+	at := *history[0].LastModifiedDate
+
+	if selected := selectRollbackVersion(history, 0, at); selected == nil || *selected.Version != 1 {
+		t.Errorf("expected version 1 at its modified time. actual: %v\n", selected)
+	}
+	if selected := selectRollbackVersion(history, 0, at.Add(-1)); selected != nil {
+		t.Errorf("expected no version before the parameter was created. actual: %v\n", selected)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/go-ini/ini"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// the IniSerial reads and writes INI files. Each [section] is a path level
+// below the file's parameter path, so a key in the section is joined to the
+// section name with NestedKeySeparator, like section/key. Keys outside of any
+// section map directly below the file's parameter path.
+type IniSerial struct{}
+
+func (s IniSerial) Load(r io.Reader) (map[string]string, error) {
+	cfg, err := ini.Load(ioutil.NopCloser(r))
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]string)
+	for _, section := range cfg.Sections() {
+		keyPrefix := ""
+		if section.Name() != ini.DEFAULT_SECTION {
+			keyPrefix = section.Name() + NestedKeySeparator
+		}
+		for _, key := range section.Keys() {
+			dict[keyPrefix+key.Name()] = key.Value()
+		}
+	}
+
+	return dict, nil
+}
+
+func (s IniSerial) Save(w io.Writer, dict *map[string]string) error {
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cfg := ini.Empty()
+	for _, key := range keys {
+		sectionName := ini.DEFAULT_SECTION
+		name := key
+		if i := strings.LastIndex(key, NestedKeySeparator); i >= 0 {
+			sectionName, name = key[:i], key[i+1:]
+		}
+
+		if _, err := cfg.Section(sectionName).NewKey(name, (*dict)[key]); err != nil {
+			return err
+		}
+	}
+
+	_, err := cfg.WriteTo(w)
+	return err
+}
+
+// sections are flattened into keys joined by NestedKeySeparator.
+func (s IniSerial) IsNested() bool {
+	return true
+}
+
+func init() {
+	RegisterSerial(IniSerial{}, ".ini", ".cfg")
+}
@@ -18,49 +18,34 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
-	"os"
+	"io"
 )
 
 type JsonSerial struct{}
 
-func (s JsonSerial) Load(path string) (map[string]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	dec := json.NewDecoder(file)
+func (s JsonSerial) Load(r io.Reader) (map[string]string, error) {
+	dec := json.NewDecoder(r)
+	// keep numbers as written, rather than as float64, which would turn large
+	// integers into exponents like 1.2345678e+07.
+	dec.UseNumber()
 	var m map[string]interface{}
 	if err := dec.Decode(&m); err != nil {
 		return nil, err
 	}
 
-	dict := make(map[string]string)
-	for k, v := range m {
-		switch v.(type) {
-		case string:
-			dict[k] = v.(string)
-		case []interface{}, map[string]interface{}:
-			return nil, errors.New("nested arrays and objects are not supported. json key " + k)
-		default:
-			dict[k] = fmt.Sprintf("%v", v)
-		}
-	}
-
-	return dict, nil
+	return flattenDoc(m)
 }
 
-func (s JsonSerial) Save(path string, dict *map[string]string) error {
-	file, err := os.Create(path)
+func (s JsonSerial) Save(w io.Writer, dict *map[string]string) error {
+	doc := nestDict(*dict)
 
-	if err != nil {
-		return err
-	}
+	enc := json.NewEncoder(w)
+	return enc.Encode(doc)
+}
 
-	enc := json.NewEncoder(file)
-	return enc.Encode(*dict)
+// nested objects are flattened into keys joined by NestedKeySeparator.
+func (s JsonSerial) IsNested() bool {
+	return true
 }
 
 func init() {
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// prefix of the annotations that carry sidecar keys, like the KMS key of each
+// SecureString, so that put can read them back.
+const k8sAnnotationPrefix = "ssmple/"
+
+var k8sDataKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+var k8sInvalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// the K8sSerial writes a ready-to-apply multi-document Kubernetes manifest,
+// with a ConfigMap holding String and StringList values and an Opaque Secret
+// holding base64-encoded SecureString values. Put reads the same manifest back,
+// treating each Secret value as a SecureString.
+type K8sSerial struct {
+	// the metadata.name and metadata.namespace of the manifests
+	Name      string
+	Namespace string
+}
+
+type k8sManifest struct {
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+type k8sMetadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// secure values are identified by their _SecureStringKeyId sidecars.
+func (s K8sSerial) NeedsKeyId() bool {
+	return true
+}
+
+// Derive a manifest name from a path like /conf/my_app.k8s.yaml, as my-app.
+func k8sNameFor(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, serialExt(base))
+	return strings.Trim(k8sInvalidNameChars.ReplaceAllString(strings.ToLower(base), "-"), "-.")
+}
+
+// The manifest name is the K8sName option, or else derived from the filename.
+func (s K8sSerial) Configure(name string, options SerialOptions) Serial {
+	manifestName := options.K8sName
+	if len(manifestName) == 0 {
+		manifestName = k8sNameFor(name)
+	}
+	return K8sSerial{Name: manifestName, Namespace: options.K8sNamespace}
+}
+
+func (s K8sSerial) Load(r io.Reader) (map[string]string, error) {
+	dict := make(map[string]string)
+	dec := yaml.NewDecoder(r)
+	for {
+		manifest := k8sManifest{}
+		if err := dec.Decode(&manifest); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		for key, value := range manifest.Metadata.Annotations {
+			if strings.HasPrefix(key, k8sAnnotationPrefix) {
+				dict[strings.TrimPrefix(key, k8sAnnotationPrefix)] = value
+			}
+		}
+
+		switch manifest.Kind {
+		case "ConfigMap":
+			for key, value := range manifest.Data {
+				dict[key] = value
+			}
+		case "Secret":
+			for key, encoded := range manifest.Data {
+				value, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return nil, errors.New("failed to decode secret data. key " + key)
+				}
+				dict[key] = string(value)
+			}
+			for key, value := range manifest.StringData {
+				dict[key] = value
+			}
+			// mark each secret as a SecureString, using the default KMS key
+			// unless an annotation specified another.
+			for key := range manifest.Data {
+				if _, ok := dict[key+KeyIdSuffix]; !ok {
+					dict[key+KeyIdSuffix] = ""
+				}
+			}
+			for key := range manifest.StringData {
+				if _, ok := dict[key+KeyIdSuffix]; !ok {
+					dict[key+KeyIdSuffix] = ""
+				}
+			}
+		default:
+			return nil, errors.New("unsupported manifest kind " + manifest.Kind + ". expected ConfigMap or Secret")
+		}
+	}
+
+	return dict, nil
+}
+
+func (s K8sSerial) Save(w io.Writer, dict *map[string]string) error {
+	configMap := k8sManifest{
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   k8sMetadata{Name: s.Name, Namespace: s.Namespace},
+		Data:       make(map[string]string)}
+	secret := k8sManifest{
+		ApiVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: s.Name, Namespace: s.Namespace},
+		Type:       "Opaque",
+		Data:       make(map[string]string)}
+
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := (*dict)[key]
+		if isSidecarKey(key) {
+			continue
+		}
+		if !k8sDataKey.MatchString(key) {
+			return errors.New("key is not a valid ConfigMap or Secret data key. key " + key)
+		}
+
+		manifest := &configMap
+		keyId, isSecure := (*dict)[key+KeyIdSuffix]
+		if isSecure {
+			manifest = &secret
+			manifest.Data[key] = base64.StdEncoding.EncodeToString([]byte(value))
+			if len(keyId) > 0 {
+				annotate(manifest, key+KeyIdSuffix, keyId)
+			}
+		} else {
+			manifest.Data[key] = value
+		}
+
+		if isStringListKey(*dict, key) {
+			annotate(manifest, key+StringListSuffix, "true")
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	for _, manifest := range []k8sManifest{configMap, secret} {
+		if len(manifest.Data) > 0 {
+			if err := enc.Encode(manifest); err != nil {
+				return err
+			}
+		}
+	}
+
+	return enc.Close()
+}
+
+func annotate(manifest *k8sManifest, key string, value string) {
+	if manifest.Metadata.Annotations == nil {
+		manifest.Metadata.Annotations = make(map[string]string)
+	}
+	manifest.Metadata.Annotations[k8sAnnotationPrefix+key] = value
+}
+
+func init() {
+	RegisterSerial(K8sSerial{}, ".k8s.yaml", ".k8s.yml")
+}
@@ -50,6 +50,15 @@ func (ka KmsMap) aliasFor(keyId string) string {
 	}
 }
 
+// Resolve a key alias to its key ID where the alias is known, so that both forms
+// of the same key compare as equal. Key IDs are returned unchanged.
+func (ka KmsMap) resolve(keyIdOrAlias string) string {
+	if strings.HasPrefix(keyIdOrAlias, "alias/") {
+		return ka.deref(keyIdOrAlias)
+	}
+	return keyIdOrAlias
+}
+
 func buildAliasList(kmss *kms.KMS, kmsMap *KmsMap) error {
 	request := kmss.ListAliasesRequest(nil)
 	result, err := request.Send()
@@ -17,15 +17,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/external"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
-	"log"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ParsedArgs struct {
@@ -35,7 +37,13 @@ type ParsedArgs struct {
 	// pass-through profile and region args to aws sdk
 	AwsProfile, AwsRegion string
 
-	// get, put, delete, clear
+	// parameter store backend: ssm, or file:///some/dir for a local emulation
+	Backend string
+
+	// true to print put and delete requests instead of sending them
+	DryRun bool
+
+	// get, put, sync, delete, clear, diff
 	SsmCmd string
 
 	// config directory for filename relative path resolution
@@ -59,11 +67,70 @@ type ParsedArgs struct {
 	// true to avoid sending secure strings on put
 	NoPutSecureString bool
 
+	// true to let sync replace a SecureString parameter with a String
+	Force bool
+
+	// true to include parameters in sub-paths below each file's parameter path
+	Recursive bool
+
+	// the separator joining sub-path levels in the keys of non-nested files
+	KeySeparator string
+
+	// the --shell-keys, --comment-removed, --k8s-name, --k8s-namespace, --toml-infer-types and --xml-comment options of the Serial for each file
+	SerialOptions SerialOptions
+
+	// true to print SecureString values in diff output
+	ShowSecrets bool
+
 	// the slice of filenames, in order of declaration
 	Filenames []string
 
+	// explicit format names by filename, from -f filename:format or --format
+	Formats map[string]string
+
+	// how keys are turned into environment variable names by exec: underscore, upper or strict
+	EnvKeys string
+
+	// the command and arguments following -- for exec
+	ExecArgs []string
+
+	// how import and copy handle parameters that already exist: fail, skip or overwrite
+	OnConflict string
+
+	// the source and target path prefixes of copy
+	From, To string
+
+	// the KMS key ID or alias to re-encrypt SecureStrings with on copy
+	ReencryptKey string
+
+	// the version number to roll back to, or 0 to roll back to ToTime
+	ToVersion int64
+
+	// the time to roll back to, when ToVersion is 0
+	ToTime time.Time
+
+	// how the existing local file is merged with SSM on get: replace, overlay or preserve-local
+	Merge string
+
+	// the mode, owner, group and backup options for writing files on get
+	WriteOptions WriteOptions
+
+	// the filename standing in for -f - when building parameter paths, which
+	// streams from stdin on put and to stdout on get. Empty if -f - is absent.
+	ParamSuffix string
+
 	// the slice of path prefixes, in order of declaration
 	Prefixes []string
+
+	// the version or label selector declared with each of the Prefixes, or
+	// empty for the latest version
+	Selectors []string
+
+	// the selectors pinned by parameter name in the --lock file
+	Locks map[string]string
+
+	// the labels to attach on label
+	Labels []string
 }
 
 const NoOptPrefix = "--no-"
@@ -72,15 +139,32 @@ func parseArgs() ParsedArgs {
 	awsProfile := ""
 	awsRegion := ""
 	useEc2Role := false
+	backend := "ssm"
+	dryRun := false
 	ssmCmd := ""
 	rawConfDir := "."
-	_, cwdErr := os.Getwd()
-	if cwdErr != nil {
-		log.Fatal("Failed to get current working directory")
-	}
 
 	filenames := make([]string, 0)
+	formats := make(map[string]string, 0)
+	defaultFormat := ""
+	paramSuffix := ""
+	merge := MergeOverlay
+	onConflict := ConflictFail
+	from := ""
+	to := ""
+	reencryptKey := ""
+	toVersion := ""
+	toTime := ""
+	envKeys := ShellKeysUpper
+	execArgs := make([]string, 0)
+	fileMode := ""
+	fileOwner := ""
+	fileGroup := ""
+	backup := false
 	prefixes := make([]string, 0)
+	selectors := make([]string, 0)
+	lockFile := ""
+	labels := make([]string, 0)
 
 	keyIdPutAll := ""
 	overwritePut := false
@@ -88,8 +172,31 @@ func parseArgs() ParsedArgs {
 	noGetSecureString := false
 	getKeyId := false
 	noPutSecureString := false
+	force := false
+	showSecrets := false
+	recursive := false
+	keySeparator := NestedKeySeparator
+	shellKeys := ShellKeysUnderscore
+	commentRemoved := false
+	k8sName := ""
+	k8sNamespace := ""
+	tomlInferTypes := false
+	xmlComment := ""
 	isHelp := false
 
+	// the first problem with the arguments is reported once the command is
+	// known, so that it exits with the status of that command
+	argErr := ""
+	optValue := func(i int) string {
+		if i+1 < len(os.Args) {
+			return os.Args[i+1]
+		}
+		if len(argErr) == 0 {
+			argErr = fmt.Sprintf("Option %s requires a value", os.Args[i])
+		}
+		return ""
+	}
+
 	for i := 1; i < len(os.Args); i++ {
 		opt := os.Args[i]
 		isNoOpt := strings.HasPrefix(opt, NoOptPrefix)
@@ -97,28 +204,96 @@ func parseArgs() ParsedArgs {
 			opt = "--" + strings.TrimPrefix(opt, NoOptPrefix)
 		}
 
+		if opt == "--" {
+			execArgs = os.Args[i+1:]
+			break
+		}
+
+		if strings.HasPrefix(opt, "--merge=") {
+			merge = strings.TrimPrefix(opt, "--merge=")
+			continue
+		}
+
 		switch opt {
 		case "-h", "--help":
 			isHelp = true
 		case "-p", "--profile":
-			awsProfile = os.Args[i+1]
+			awsProfile = optValue(i)
 			i++
 		case "-r", "--region":
-			awsRegion = os.Args[i+1]
+			awsRegion = optValue(i)
 			i++
 		case "--use-ec2-role":
 			useEc2Role = !isNoOpt
+		case "--dry-run":
+			dryRun = !isNoOpt
+		case "--backend":
+			backend = optValue(i)
+			i++
 		case "-C", "--conf-dir":
-			rawConfDir = os.Args[i+1]
+			rawConfDir = optValue(i)
 			i++
 		case "-f", "--filename":
-			filenames = append(filenames, os.Args[i+1])
+			filename, format := splitFormatSuffix(optValue(i))
+			filenames = append(filenames, filename)
+			if len(format) > 0 {
+				formats[filename] = format
+			}
+			i++
+		case "--format":
+			defaultFormat = optValue(i)
+			i++
+		case "--env-keys":
+			envKeys = optValue(i)
+			i++
+		case "--from":
+			from = optValue(i)
+			i++
+		case "--to":
+			to = optValue(i)
+			i++
+		case "--to-version":
+			toVersion = optValue(i)
+			i++
+		case "--to-time":
+			toTime = optValue(i)
+			i++
+		case "--reencrypt-key":
+			reencryptKey = optValue(i)
+			i++
+		case "--on-conflict":
+			onConflict = optValue(i)
+			i++
+		case "--merge":
+			merge = optValue(i)
+			i++
+		case "--file-mode":
+			fileMode = optValue(i)
+			i++
+		case "--file-owner":
+			fileOwner = optValue(i)
+			i++
+		case "--file-group":
+			fileGroup = optValue(i)
+			i++
+		case "--backup":
+			backup = !isNoOpt
+		case "--param-suffix":
+			paramSuffix = optValue(i)
 			i++
 		case "-s", "--starts-with":
-			prefixes = append(prefixes, os.Args[i+1])
+			prefix, selector := splitSelector(optValue(i))
+			prefixes = append(prefixes, prefix)
+			selectors = append(selectors, selector)
+			i++
+		case "--lock":
+			lockFile = optValue(i)
+			i++
+		case "--label":
+			labels = append(labels, optValue(i))
 			i++
 		case "-k", "--key-id-put-all":
-			keyIdPutAll = os.Args[i+1]
+			keyIdPutAll = optValue(i)
 			i++
 		case "-o", "--overwrite-put":
 			overwritePut = !isNoOpt
@@ -130,14 +305,51 @@ func parseArgs() ParsedArgs {
 			getKeyId = !isNoOpt
 		case "--put-secure-string":
 			noPutSecureString = isNoOpt
-		case "get", "put", "delete", "clear":
+		case "--force":
+			force = !isNoOpt
+		case "-R", "--recursive":
+			recursive = !isNoOpt
+		case "--key-separator":
+			keySeparator = optValue(i)
+			i++
+		case "--shell-keys":
+			shellKeys = optValue(i)
+			i++
+		case "--comment-removed":
+			commentRemoved = !isNoOpt
+		case "--k8s-name":
+			k8sName = optValue(i)
+			i++
+		case "--k8s-namespace":
+			k8sNamespace = optValue(i)
+			i++
+		case "--toml-infer-types":
+			tomlInferTypes = !isNoOpt
+		case "--xml-comment":
+			xmlComment = optValue(i)
+			i++
+		case "--show-secrets":
+			showSecrets = !isNoOpt
+		case "get", "put", "sync", "delete", "clear", "diff", "exec", "render", "export", "import", "copy", "history", "rollback", "label":
 			ssmCmd = opt
+		case "promote":
+			ssmCmd = "copy"
 		default:
-			usage(ssmCmd)
-			log.Fatal(fmt.Sprintf("Unrecognized option %s", opt))
+			if len(argErr) == 0 {
+				argErr = fmt.Sprintf("Unrecognized option %s", opt)
+			}
 		}
 	}
 
+	if len(argErr) > 0 {
+		usage(ssmCmd)
+		fatalf(ssmCmd, "%s", argErr)
+	}
+
+	if _, cwdErr := os.Getwd(); cwdErr != nil {
+		fatalf(ssmCmd, "Failed to get current working directory")
+	}
+
 	if isHelp {
 		usage(ssmCmd)
 		os.Exit(0)
@@ -150,31 +362,273 @@ func parseArgs() ParsedArgs {
 
 	confDir, confErr := filepath.Abs(rawConfDir)
 	if confErr != nil {
-		log.Fatal("Failed to resolve confDir "+rawConfDir, confErr)
+		fatalf(ssmCmd, "Failed to resolve confDir %s. reason: %s", rawConfDir, confErr)
+	}
+
+	if backend != "ssm" && !strings.HasPrefix(backend, FileBackendScheme) {
+		fatalf(ssmCmd, "Unrecognized backend %s. expected ssm or %s<dir>", backend, FileBackendScheme)
+	}
+
+	if len(keySeparator) == 0 {
+		fatalf(ssmCmd, "--key-separator must not be empty, like / or .")
+	}
+
+	switch shellKeys {
+	case ShellKeysUnderscore, ShellKeysUpper, ShellKeysStrict:
+	default:
+		fatalf(ssmCmd, "Unrecognized --shell-keys mode %s. expected %s, %s or %s",
+			shellKeys, ShellKeysUnderscore, ShellKeysUpper, ShellKeysStrict)
+	}
+
+	switch envKeys {
+	case ShellKeysUnderscore, ShellKeysUpper, ShellKeysStrict:
+	default:
+		fatalf(ssmCmd, "Unrecognized --env-keys mode %s. expected %s, %s or %s",
+			envKeys, ShellKeysUnderscore, ShellKeysUpper, ShellKeysStrict)
+	}
+
+	if len(execArgs) > 0 && ssmCmd != "exec" {
+		fatalf(ssmCmd, "Arguments after -- are only accepted by the exec command")
+	}
+
+	var rollbackVersion int64
+	var rollbackTime time.Time
+	if ssmCmd == "rollback" {
+		if (len(toVersion) == 0) == (len(toTime) == 0) {
+			fatalf(ssmCmd, "rollback requires exactly one of --to-version <number> or --to-time <time>, like 2018-06-01T12:00:00Z")
+		}
+		if len(toVersion) > 0 {
+			version, err := strconv.ParseInt(toVersion, 10, 64)
+			if err != nil || version <= 0 {
+				fatalf(ssmCmd, "Invalid --to-version %s. expected a positive version number", toVersion)
+			}
+			rollbackVersion = version
+		} else {
+			t, err := time.Parse(time.RFC3339, toTime)
+			if err != nil {
+				fatalf(ssmCmd, "Invalid --to-time %s. expected an RFC 3339 time, like 2018-06-01T12:00:00Z", toTime)
+			}
+			rollbackTime = t
+		}
+	}
+
+	switch onConflict {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+	default:
+		fatalf(ssmCmd, "Unrecognized --on-conflict policy %s. expected %s, %s or %s",
+			onConflict, ConflictFail, ConflictSkip, ConflictOverwrite)
+	}
+
+	switch merge {
+	case MergeReplace, MergeOverlay, MergePreserveLocal:
+	default:
+		fatalf(ssmCmd, "Unrecognized --merge strategy %s. expected %s, %s or %s",
+			merge, MergeReplace, MergeOverlay, MergePreserveLocal)
+	}
+
+	if len(defaultFormat) > 0 {
+		if _, ok := GetSerialForFormat(defaultFormat); !ok {
+			fatalf(ssmCmd, "Unrecognized --format %s. expected a supported extension without the leading period, like yaml", defaultFormat)
+		}
+		for _, filename := range filenames {
+			if _, ok := formats[filename]; !ok {
+				formats[filename] = defaultFormat
+			}
+		}
 	}
 
-	if len(prefixes) == 0 {
-		log.Fatal("At least one -s/--starts-with path is required, like /ecs/dev/myapp")
+	writeOptions := NewWriteOptions()
+	writeOptions.Backup = backup
+	if len(fileMode) > 0 {
+		mode, err := strconv.ParseUint(fileMode, 8, 32)
+		if err != nil || mode == 0 || mode > 0777 {
+			fatalf(ssmCmd, "Invalid --file-mode %s. expected octal permissions, like 0640", fileMode)
+		}
+		writeOptions.Mode = os.FileMode(mode)
+	}
+	if len(fileOwner) > 0 {
+		uid, err := lookupUid(fileOwner)
+		if err != nil {
+			fatalf(ssmCmd, "Invalid --file-owner %s. reason: %s", fileOwner, err)
+		}
+		writeOptions.Uid = uid
+	}
+	if len(fileGroup) > 0 {
+		gid, err := lookupGid(fileGroup)
+		if err != nil {
+			fatalf(ssmCmd, "Invalid --file-group %s. reason: %s", fileGroup, err)
+		}
+		writeOptions.Gid = gid
 	}
 
-	if len(filenames) == 0 {
-		log.Fatal("At least one -f/--filename argument is required, like instance.properties")
+	paramSuffix, streamErr := resolveStreamFilename(ssmCmd, filenames, formats, paramSuffix)
+	if streamErr != nil {
+		fatalf(ssmCmd, "%s", streamErr)
 	}
 
+	if ssmCmd == "copy" {
+		if len(from) == 0 || len(to) == 0 || len(prefixes) > 0 {
+			fatalf(ssmCmd, "copy requires --from and --to path prefixes instead of -s/--starts-with, like --from /ep/conf/preprod --to /ep/conf/prod")
+		}
+	} else if len(prefixes) == 0 {
+		fatalf(ssmCmd, "At least one -s/--starts-with path is required, like /ecs/dev/myapp")
+	}
+
+	if len(filenames) == 0 && ssmCmd != "copy" {
+		fatalf(ssmCmd, "At least one -f/--filename argument is required, like instance.properties")
+	}
+
+	var locks map[string]string
+	switch ssmCmd {
+	case "get", "diff", "exec", "render", "label":
+		for _, selector := range selectors {
+			if len(selector) == 0 {
+				continue
+			}
+			if err := validateSelector(selector); err != nil {
+				fatalf(ssmCmd, "Invalid -s/--starts-with selector. reason: %s", err)
+			}
+		}
+		if len(lockFile) > 0 {
+			pinned, err := readLockFile(lockFile)
+			if err != nil {
+				fatalf(ssmCmd, "Failed to read lock file %s. reason: %s", lockFile, err)
+			}
+			locks = pinned
+		}
+	default:
+		for _, selector := range selectors {
+			if len(selector) > 0 {
+				fatalf(ssmCmd, "%s does not accept a version or label selector on -s/--starts-with, like /ep/conf:release-42", ssmCmd)
+			}
+		}
+		if len(lockFile) > 0 {
+			fatalf(ssmCmd, "%s does not accept --lock", ssmCmd)
+		}
+	}
+
+	if ssmCmd == "label" {
+		if len(labels) == 0 {
+			fatalf(ssmCmd, "label requires at least one --label argument, like --label release-42")
+		}
+		if len(labels) > maxLabelsPerVersion {
+			fatalf(ssmCmd, "label accepts at most %d --label arguments", maxLabelsPerVersion)
+		}
+		for _, label := range labels {
+			if !isValidLabel(label) {
+				fatalf(ssmCmd, "Invalid --label %s. labels may contain letters, numbers, periods, hyphens and underscores, "+
+					"and may not begin with a number, aws or ssm", label)
+			}
+		}
+	}
+
+	serialOptions := SerialOptions{
+		ShellKeys:      shellKeys,
+		CommentRemoved: commentRemoved,
+		K8sName:        k8sName,
+		K8sNamespace:   k8sNamespace,
+		TomlInferTypes: tomlInferTypes,
+		XmlComment:     xmlComment}
+
 	return ParsedArgs{
 		UseEc2Role:        useEc2Role,
 		AwsProfile:        awsProfile,
 		AwsRegion:         awsRegion,
+		Backend:           backend,
+		DryRun:            dryRun,
 		SsmCmd:            ssmCmd,
 		ConfDir:           confDir,
 		Filenames:         filenames,
+		Formats:           formats,
+		ParamSuffix:       paramSuffix,
+		EnvKeys:           envKeys,
+		ExecArgs:          execArgs,
+		OnConflict:        onConflict,
+		From:              from,
+		To:                to,
+		ReencryptKey:      reencryptKey,
+		ToVersion:         rollbackVersion,
+		ToTime:            rollbackTime,
+		Merge:             merge,
+		WriteOptions:      writeOptions,
 		Prefixes:          prefixes,
+		Selectors:         selectors,
+		Locks:             locks,
+		Labels:            labels,
 		KeyIdPutAll:       keyIdPutAll,
 		OverwritePut:      overwritePut,
 		ClearOnPut:        clearOnPut,
 		NoGetSecureString: noGetSecureString,
 		GetKeyId:          getKeyId,
-		NoPutSecureString: noPutSecureString}
+		NoPutSecureString: noPutSecureString,
+		Force:             force,
+		Recursive:         recursive,
+		KeySeparator:      keySeparator,
+		SerialOptions:     serialOptions,
+		ShowSecrets:       showSecrets}
+}
+
+// Replace a -f - filename with the --param-suffix that stands in for it when
+// building parameter paths, moving its format along. Bundles hold whole
+// parameter names, so export and import do not require a --param-suffix.
+// Returns the param suffix, which is empty when there is no -f -.
+func resolveStreamFilename(ssmCmd string, filenames []string, formats map[string]string, paramSuffix string) (string, error) {
+	streams := 0
+	for i, filename := range filenames {
+		if filename != StreamFilename {
+			continue
+		}
+		streams++
+		if streams > 1 {
+			return "", errors.New("-f - may only be specified once")
+		}
+		if _, ok := formats[StreamFilename]; !ok {
+			return "", errors.New("-f - requires a format, like -f -:yaml or --format yaml")
+		}
+		if len(paramSuffix) == 0 {
+			if ssmCmd != "export" && ssmCmd != "import" {
+				return "", errors.New("-f - requires a --param-suffix to build parameter paths, like myapp")
+			}
+			paramSuffix = StreamFilename
+			continue
+		}
+		for _, other := range filenames {
+			if other == paramSuffix {
+				return "", errors.New("--param-suffix " + paramSuffix + " must not also be specified as a -f filename")
+			}
+		}
+		filenames[i] = paramSuffix
+		formats[paramSuffix] = formats[StreamFilename]
+		delete(formats, StreamFilename)
+	}
+	if streams == 0 {
+		return "", nil
+	}
+	return paramSuffix, nil
+}
+
+// Resolve a user name or numeric uid.
+func lookupUid(owner string) (int, error) {
+	if uid, err := strconv.Atoi(owner); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// Resolve a group name or numeric gid.
+func lookupGid(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(g.Gid)
 }
 
 func getAwsConfigResolvers(authEc2 bool) []external.AWSConfigResolver {
@@ -199,6 +653,15 @@ func getAwsConfigResolvers(authEc2 bool) []external.AWSConfigResolver {
 func main() {
 	prefs := parseArgs()
 
+	if strings.HasPrefix(prefs.Backend, FileBackendScheme) {
+		fileParams, err := NewFileParameterStore(prefs.Backend)
+		if err != nil {
+			fatalf(prefs.SsmCmd, "%s\n", err)
+		}
+		execCmd(prefs, fileParams, nil)
+		return
+	}
+
 	var cfgs external.Configs
 	var err error
 
@@ -211,28 +674,49 @@ func main() {
 	}
 
 	if cfgs, err = cfgs.AppendFromLoaders(external.DefaultConfigLoaders); err != nil {
-		log.Fatal(err)
+		fatalf(prefs.SsmCmd, "%s\n", err)
 	}
 
 	resolvers := getAwsConfigResolvers(prefs.UseEc2Role)
 
 	awsCfg, err := cfgs.ResolveAWSConfig(resolvers)
 	if err != nil {
-		log.Fatal(err)
+		fatalf(prefs.SsmCmd, "%s\n", err)
 	}
 
-	execCmd(prefs, awsCfg)
+	execCmd(prefs, SsmParameterStore{Ssms: ssm.New(awsCfg)}, kms.New(awsCfg))
 }
 
-func execCmd(prefs ParsedArgs, cfg aws.Config) {
-	ssms := ssm.New(cfg)
-	kmss := kms.New(cfg)
+// execute the parsed command against the given ParameterStore. When kmss is nil,
+// KMS aliases are not resolved and keyIds are passed through unchanged.
+func execCmd(prefs ParsedArgs, params ParameterStore, kmss *kms.KMS) {
+	if prefs.DryRun {
+		params = NewDryRunParameterStore(params, os.Stdout)
+	}
 
 	fileStores := make(map[string]*FileStore, len(prefs.Filenames))
 	for _, fn := range prefs.Filenames {
 		fs := NewFileStore(prefs.ConfDir, fn)
+		if fn == prefs.ParamSuffix {
+			// stream to stdout on get and export, and otherwise read from stdin.
+			if cmd := strings.ToLower(prefs.SsmCmd); cmd == "get" || cmd == "export" {
+				fs = NewStreamFileStore(fn, prefs.Formats[fn], nil, os.Stdout)
+			} else {
+				fs = NewStreamFileStore(fn, prefs.Formats[fn], os.Stdin, nil)
+			}
+		}
+		fs.Format = prefs.Formats[fn]
+		fs.Options = prefs.WriteOptions
+		fs.SerialOptions = prefs.SerialOptions
+		// exec and render only name files to build parameter paths, without
+		// reading them, and export and import read and write bundles instead.
+		switch strings.ToLower(prefs.SsmCmd) {
+		case "exec", "render", "export", "import", "copy", "label":
+			fileStores[fn] = &fs
+			continue
+		}
 		if err := fs.Load(); err != nil {
-			log.Fatalf("Failed to load file store for name %s. reason: %s", fn, err)
+			fatalf(prefs.SsmCmd, "Failed to load file store for name %s. reason: %s", fn, err)
 		}
 		fileStores[fn] = &fs
 	}
@@ -244,25 +728,60 @@ func execCmd(prefs ParsedArgs, cfg aws.Config) {
 	ctx := CmdContext{
 		Prefs:  prefs,
 		Stores: fileStores,
-		Ssms:   ssms,
+		Params: params,
 		KmsMap: kmsMap}
 
 	switch strings.ToLower(prefs.SsmCmd) {
 	case "get":
-		if !prefs.NoGetSecureString {
+		if !prefs.NoGetSecureString && kmss != nil {
 			buildAliasList(kmss, &kmsMap)
 		}
 		doGet(&ctx)
 	case "put":
-		if !prefs.NoPutSecureString {
+		if !prefs.NoPutSecureString && kmss != nil {
 			buildAliasList(kmss, &kmsMap)
 		}
 		doPut(&ctx)
+	case "sync":
+		if !prefs.NoPutSecureString && kmss != nil {
+			buildAliasList(kmss, &kmsMap)
+		}
+		doSync(&ctx)
+	case "diff":
+		if !prefs.NoGetSecureString && kmss != nil {
+			buildAliasList(kmss, &kmsMap)
+		}
+		doDiff(&ctx)
+	case "exec":
+		doExec(&ctx)
+	case "render":
+		if !prefs.NoGetSecureString && kmss != nil {
+			buildAliasList(kmss, &kmsMap)
+		}
+		doRender(&ctx)
+	case "export":
+		if kmss != nil {
+			buildAliasList(kmss, &kmsMap)
+		}
+		doExport(&ctx)
+	case "import":
+		doImport(&ctx)
+	case "history":
+		doHistory(&ctx)
+	case "label":
+		doLabel(&ctx)
+	case "rollback":
+		doRollback(&ctx)
+	case "copy":
+		if kmss != nil {
+			buildAliasList(kmss, &kmsMap)
+		}
+		doCopy(&ctx)
 	case "delete":
 		doDelete(&ctx)
 	case "clear":
 		doClear(&ctx)
 	default:
-		log.Fatalf("Unknown command %s", prefs.SsmCmd)
+		fatalf(prefs.SsmCmd, "Unknown command %s", prefs.SsmCmd)
 	}
 }
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestResolveStreamFilename(t *testing.T) {
+	filenames := []string{"-", "ecs.properties"}
+	formats := map[string]string{"-": "yaml"}
+	if _, err := resolveStreamFilename("get", filenames, formats, ""); err == nil {
+		t.Error("expected get with -f - to require a --param-suffix")
+	}
+
+	paramSuffix, err := resolveStreamFilename("get", filenames, formats, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paramSuffix != "myapp" || filenames[0] != "myapp" || formats["myapp"] != "yaml" {
+		t.Errorf("expected -f - to be replaced by the param suffix. actual: %s %v %v\n", paramSuffix, filenames, formats)
+	}
+
+	for _, ssmCmd := range []string{"export", "import"} {
+		filenames := []string{"-"}
+		formats := map[string]string{"-": "json"}
+		paramSuffix, err := resolveStreamFilename(ssmCmd, filenames, formats, "")
+		if err != nil {
+			t.Fatalf("expected %s to accept -f - without a --param-suffix. reason: %s\n", ssmCmd, err)
+		}
+		if paramSuffix != StreamFilename || filenames[0] != StreamFilename || formats[StreamFilename] != "json" {
+			t.Errorf("expected %s to keep -f - as the filename. actual: %s %v %v\n", ssmCmd, paramSuffix, filenames, formats)
+		}
+	}
+
+	if _, err := resolveStreamFilename("export", []string{"-"}, map[string]string{}, ""); err == nil {
+		t.Error("expected -f - to require a format")
+	}
+}
@@ -0,0 +1,334 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultSecureStringKeyId = "alias/aws/ssm"
+
+// the MemParameterStore is a ParameterStore implementation that keeps every
+// version of every parameter in memory. It mimics the SSM validation rules
+// that matter to ssmple, so that the operations can be exercised without
+// an AWS account.
+type MemParameterStore struct {
+	// the version history of each parameter by name, oldest first.
+	History map[string][]ssm.ParameterHistory
+
+	// the tags of each parameter by name.
+	Tags map[string][]ssm.Tag
+}
+
+func NewMemParameterStore() *MemParameterStore {
+	return &MemParameterStore{
+		History: make(map[string][]ssm.ParameterHistory, 0),
+		Tags:    make(map[string][]ssm.Tag, 0)}
+}
+
+// return the latest version of the named parameter, if it exists.
+func (ps *MemParameterStore) latest(name string) (ssm.ParameterHistory, bool) {
+	versions := ps.History[name]
+	if len(versions) == 0 {
+		return ssm.ParameterHistory{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+func toParameter(hist ssm.ParameterHistory) ssm.Parameter {
+	return ssm.Parameter{
+		LastModifiedDate: hist.LastModifiedDate,
+		Name:             hist.Name,
+		Type:             hist.Type,
+		Value:            hist.Value,
+		Version:          hist.Version}
+}
+
+func toParameterMetadata(hist ssm.ParameterHistory) ssm.ParameterMetadata {
+	return ssm.ParameterMetadata{
+		AllowedPattern:   hist.AllowedPattern,
+		Description:      hist.Description,
+		KeyId:            hist.KeyId,
+		LastModifiedDate: hist.LastModifiedDate,
+		LastModifiedUser: hist.LastModifiedUser,
+		Name:             hist.Name,
+		Tier:             hist.Tier,
+		Type:             hist.Type,
+		Version:          hist.Version}
+}
+
+// return the sorted names of all parameters that currently exist.
+func (ps *MemParameterStore) names() []string {
+	names := make([]string, 0, len(ps.History))
+	for name, versions := range ps.History {
+		if len(versions) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (ps *MemParameterStore) GetParametersByPath(path string, recursive bool) ([]ssm.Parameter, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("ValidationException: parameter path must begin with '/'. path " + path)
+	}
+
+	base := strings.TrimSuffix(path, "/") + "/"
+	var params []ssm.Parameter
+	for _, name := range ps.names() {
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		if !recursive && strings.ContainsRune(strings.TrimPrefix(name, base), '/') {
+			continue
+		}
+		hist, _ := ps.latest(name)
+		params = append(params, toParameter(hist))
+	}
+
+	return params, nil
+}
+
+// return the version of the named parameter picked by selector, which is
+// either a version number or a label. An empty selector picks the latest.
+func (ps *MemParameterStore) selected(name string, selector string) (ssm.ParameterHistory, bool) {
+	if len(selector) == 0 {
+		return ps.latest(name)
+	}
+
+	version, byVersion := selectorVersion(selector)
+	for _, hist := range ps.History[name] {
+		if byVersion && *hist.Version == version {
+			return hist, true
+		}
+		for _, label := range hist.Labels {
+			if !byVersion && label == selector {
+				return hist, true
+			}
+		}
+	}
+	return ssm.ParameterHistory{}, false
+}
+
+func (ps *MemParameterStore) GetParameters(names []string) ([]ssm.Parameter, []string, error) {
+	var params []ssm.Parameter
+	var invalid []string
+	for _, selectorName := range names {
+		name, selector := splitSelector(selectorName)
+		if hist, ok := ps.selected(name, selector); ok {
+			param := toParameter(hist)
+			if len(selector) > 0 {
+				paramSelector := SelectorSeparator + selector
+				param.Selector = &paramSelector
+			}
+			params = append(params, param)
+		} else {
+			invalid = append(invalid, selectorName)
+		}
+	}
+
+	return params, invalid, nil
+}
+
+func (ps *MemParameterStore) PutParameter(input *ssm.PutParameterInput) error {
+	if input.Name == nil || len(*input.Name) == 0 {
+		return errors.New("ValidationException: parameter name is required")
+	}
+
+	name := *input.Name
+	if input.Value == nil || len(*input.Value) == 0 {
+		return errors.New("ValidationException: parameter value must not be empty. name " + name)
+	}
+
+	prev, exists := ps.latest(name)
+	overwrite := input.Overwrite != nil && *input.Overwrite
+	if exists && !overwrite {
+		return errors.New("ParameterAlreadyExists: the parameter already exists. name " + name)
+	}
+	if overwrite && len(input.Tags) > 0 {
+		return errors.New("ValidationException: tags may not be specified with overwrite. name " + name)
+	}
+
+	paramType := input.Type
+	if len(paramType) == 0 {
+		if !exists {
+			return errors.New("ValidationException: parameter type is required. name " + name)
+		}
+		paramType = prev.Type
+	}
+
+	var keyId *string
+	if paramType == ssm.ParameterTypeSecureString {
+		if input.KeyId != nil && len(*input.KeyId) > 0 {
+			keyId = input.KeyId
+		} else {
+			defaultKeyId := DefaultSecureStringKeyId
+			keyId = &defaultKeyId
+		}
+	} else if input.KeyId != nil {
+		return errors.New("ValidationException: a KeyId may only be specified for SecureString parameters. name " + name)
+	}
+
+	version := int64(1)
+	if exists {
+		version = *prev.Version + 1
+	}
+
+	nameCopy := name
+	valueCopy := *input.Value
+	modified := time.Now()
+	hist := ssm.ParameterHistory{
+		AllowedPattern:   input.AllowedPattern,
+		Description:      input.Description,
+		KeyId:            keyId,
+		LastModifiedDate: &modified,
+		Name:             &nameCopy,
+		Tier:             input.Tier,
+		Type:             paramType,
+		Value:            &valueCopy,
+		Version:          &version}
+
+	ps.History[name] = append(ps.History[name], hist)
+	if len(input.Tags) > 0 {
+		return ps.AddTags(name, input.Tags)
+	}
+	return nil
+}
+
+func (ps *MemParameterStore) DeleteParameters(names []string) error {
+	for _, name := range names {
+		delete(ps.History, name)
+		delete(ps.Tags, name)
+	}
+	return nil
+}
+
+func (ps *MemParameterStore) DescribeParameter(name string) (*ssm.ParameterMetadata, error) {
+	if hist, ok := ps.latest(name); ok {
+		meta := toParameterMetadata(hist)
+		return &meta, nil
+	}
+	return nil, nil
+}
+
+func (ps *MemParameterStore) GetParameterHistory(name string) ([]ssm.ParameterHistory, error) {
+	versions := ps.History[name]
+	if len(versions) == 0 {
+		return nil, errors.New("ParameterNotFound: name " + name)
+	}
+
+	history := make([]ssm.ParameterHistory, len(versions))
+	copy(history, versions)
+	return history, nil
+}
+
+func (ps *MemParameterStore) ListTags(name string) ([]ssm.Tag, error) {
+	if _, ok := ps.latest(name); !ok {
+		return nil, errors.New("InvalidResourceId: name " + name)
+	}
+
+	tags := make([]ssm.Tag, len(ps.Tags[name]))
+	copy(tags, ps.Tags[name])
+	return tags, nil
+}
+
+func (ps *MemParameterStore) AddTags(name string, tags []ssm.Tag) error {
+	if _, ok := ps.latest(name); !ok {
+		return errors.New("InvalidResourceId: name " + name)
+	}
+
+	for _, tag := range tags {
+		if tag.Key == nil || tag.Value == nil {
+			return errors.New("ValidationException: tags require a key and value. name " + name)
+		}
+		replaced := false
+		for i, existing := range ps.Tags[name] {
+			if *existing.Key == *tag.Key {
+				ps.Tags[name][i] = tag
+				replaced = true
+			}
+		}
+		if !replaced {
+			ps.Tags[name] = append(ps.Tags[name], tag)
+		}
+	}
+	return nil
+}
+
+func (ps *MemParameterStore) LabelParameterVersion(name string, version int64, labels []string) error {
+	versions := ps.History[name]
+	if len(versions) == 0 {
+		return errors.New("ParameterNotFound: name " + name)
+	}
+
+	target := len(versions) - 1
+	if version != 0 {
+		target = -1
+		for i, hist := range versions {
+			if *hist.Version == version {
+				target = i
+			}
+		}
+		if target < 0 {
+			return fmt.Errorf("ParameterVersionNotFound: version %d. name %s", version, name)
+		}
+	}
+
+	targetLabels := append([]string{}, versions[target].Labels...)
+	for _, label := range labels {
+		if !isValidLabel(label) {
+			return errors.New("ValidationException: invalid label " + label + ". name " + name)
+		}
+		if !containsLabel(targetLabels, label) {
+			targetLabels = append(targetLabels, label)
+		}
+	}
+	if len(targetLabels) > maxLabelsPerVersion {
+		return fmt.Errorf("ParameterVersionLabelLimitExceeded: version %d. name %s", *versions[target].Version, name)
+	}
+
+	for i := range versions {
+		if i == target {
+			versions[i].Labels = targetLabels
+			continue
+		}
+
+		var kept []string
+		for _, label := range versions[i].Labels {
+			if !containsLabel(labels, label) {
+				kept = append(kept, label)
+			}
+		}
+		versions[i].Labels = kept
+	}
+	return nil
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, existing := range labels {
+		if existing == label {
+			return true
+		}
+	}
+	return false
+}
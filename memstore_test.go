@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"testing"
+)
+
+func TestMemParameterStore(t *testing.T) {
+	ps := NewMemParameterStore()
+
+	put := func(name string, value string, overwrite bool) error {
+		input := ssm.PutParameterInput{
+			Name:      &name,
+			Value:     &value,
+			Overwrite: &overwrite,
+			Type:      ssm.ParameterTypeString}
+		return ps.PutParameter(&input)
+	}
+
+	if err := put("/a/one", "1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("/a/b/two", "2", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("/a/one", "uno", false); err == nil {
+		t.Error("put should fail for an existing parameter without overwrite")
+	}
+	if err := put("/a/one", "uno", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := put("/a/empty", "", false); err == nil {
+		t.Error("put should fail for an empty value")
+	}
+
+	if params, _ := ps.GetParametersByPath("/a", false); len(params) != 1 || *params[0].Value != "uno" {
+		t.Errorf("non-recursive path should return only /a/one. actual: %v\n", params)
+	}
+	if params, _ := ps.GetParametersByPath("/a", true); len(params) != 2 {
+		t.Errorf("recursive path should return two parameters. actual: %v\n", params)
+	}
+
+	params, invalid, _ := ps.GetParameters([]string{"/a/one", "/a/missing"})
+	if len(params) != 1 || len(invalid) != 1 || invalid[0] != "/a/missing" {
+		t.Errorf("expected one found and one invalid parameter. found: %v, invalid: %v\n", params, invalid)
+	}
+
+	history, _ := ps.GetParameterHistory("/a/one")
+	if len(history) != 2 || *history[0].Value != "1" || *history[1].Version != 2 {
+		t.Errorf("expected two versions of /a/one. actual: %v\n", history)
+	}
+
+	ps.DeleteParameters([]string{"/a/one", "/a/missing"})
+	if meta, _ := ps.DescribeParameter("/a/one"); meta != nil {
+		t.Errorf("/a/one should have been deleted. actual: %v\n", meta)
+	}
+}
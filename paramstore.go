@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"strings"
+)
+
+// a ParameterStore abstracts the subset of the SSM Parameter Store API that
+// the ssmple operations depend on, so that they can run against AWS or against
+// an alternative backend.
+type ParameterStore interface {
+	// List the parameters below a path, with SecureString values decrypted.
+	// When recursive is false, only the direct children of the path are returned.
+	GetParametersByPath(path string, recursive bool) ([]ssm.Parameter, error)
+
+	// Get parameters by name, with SecureString values decrypted. Names that
+	// could not be found are returned in the second slice.
+	GetParameters(names []string) ([]ssm.Parameter, []string, error)
+
+	// Create or update a single parameter.
+	PutParameter(input *ssm.PutParameterInput) error
+
+	// Delete parameters by name. Names that do not exist are ignored.
+	DeleteParameters(names []string) error
+
+	// Describe the metadata of a single parameter, including its KMS key ID.
+	// Returns nil if the parameter does not exist.
+	DescribeParameter(name string) (*ssm.ParameterMetadata, error)
+
+	// List every version of a single parameter, oldest first.
+	GetParameterHistory(name string) ([]ssm.ParameterHistory, error)
+
+	// List the tags of a single parameter.
+	ListTags(name string) ([]ssm.Tag, error)
+
+	// Add tags to a single parameter, replacing the values of existing tag keys.
+	AddTags(name string, tags []ssm.Tag) error
+
+	// Attach labels to a version of a single parameter, moving them off any
+	// other version of it. A version of 0 labels the latest version.
+	LabelParameterVersion(name string, version int64, labels []string) error
+}
+
+// the maximum number of names accepted by a single GetParameters or
+// DeleteParameters request.
+const ssmBatchSize = 10
+
+// the SsmParameterStore is the ParameterStore implementation backed by the
+// AWS SSM Parameter Store API.
+type SsmParameterStore struct {
+	Ssms *ssm.SSM
+}
+
+func (ps SsmParameterStore) GetParametersByPath(path string, recursive bool) ([]ssm.Parameter, error) {
+	var paramsForPath []ssm.Parameter
+	maxResults := int64(10)
+	withDecryption := true
+
+	input := ssm.GetParametersByPathInput{
+		MaxResults:     &maxResults,
+		Path:           &path,
+		WithDecryption: &withDecryption,
+		Recursive:      &recursive}
+
+	request := ps.Ssms.GetParametersByPathRequest(&input)
+	pager := request.Paginate()
+	for pager.Next() {
+		result := pager.CurrentPage()
+		if len(result.Parameters) > 0 {
+			paramsForPath = append(paramsForPath, result.Parameters...)
+		}
+	}
+
+	if pager.Err() != nil {
+		return paramsForPath, pager.Err()
+	} else {
+		return paramsForPath, nil
+	}
+}
+
+func (ps SsmParameterStore) GetParameters(names []string) ([]ssm.Parameter, []string, error) {
+	var params []ssm.Parameter
+	var invalid []string
+	withDecryption := true
+
+	for b := 0; b < len(names); b += ssmBatchSize {
+		end := b + ssmBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		input := ssm.GetParametersInput{
+			Names:          names[b:end],
+			WithDecryption: &withDecryption}
+
+		result, err := ps.Ssms.GetParametersRequest(&input).Send()
+		if err != nil {
+			return params, invalid, err
+		}
+
+		params = append(params, result.Parameters...)
+		invalid = append(invalid, result.InvalidParameters...)
+	}
+
+	return params, invalid, nil
+}
+
+func (ps SsmParameterStore) PutParameter(input *ssm.PutParameterInput) error {
+	_, err := ps.Ssms.PutParameterRequest(input).Send()
+	return err
+}
+
+func (ps SsmParameterStore) DeleteParameters(names []string) error {
+	for b := 0; b < len(names); b += ssmBatchSize {
+		end := b + ssmBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+
+		input := ssm.DeleteParametersInput{Names: names[b:end]}
+		if _, err := ps.Ssms.DeleteParametersRequest(&input).Send(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ps SsmParameterStore) DescribeParameter(name string) (*ssm.ParameterMetadata, error) {
+	filterKey, _ := ssm.ParametersFilterKeyName.MarshalValue()
+	filterOption := "Equals"
+
+	input := ssm.DescribeParametersInput{}
+	input.ParameterFilters = append(input.ParameterFilters,
+		ssm.ParameterStringFilter{
+			Key:    &filterKey,
+			Option: &filterOption,
+			Values: []string{name}})
+
+	result, err := ps.Ssms.DescribeParametersRequest(&input).Send()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Parameters) > 0 {
+		return &result.Parameters[0], nil
+	}
+
+	return nil, nil
+}
+
+func (ps SsmParameterStore) GetParameterHistory(name string) ([]ssm.ParameterHistory, error) {
+	var history []ssm.ParameterHistory
+	withDecryption := true
+
+	input := ssm.GetParameterHistoryInput{
+		Name:           &name,
+		WithDecryption: &withDecryption}
+
+	for {
+		result, err := ps.Ssms.GetParameterHistoryRequest(&input).Send()
+		if err != nil {
+			return history, err
+		}
+
+		history = append(history, result.Parameters...)
+		if result.NextToken == nil || len(*result.NextToken) == 0 {
+			return history, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+func (ps SsmParameterStore) ListTags(name string) ([]ssm.Tag, error) {
+	input := ssm.ListTagsForResourceInput{
+		ResourceId:   &name,
+		ResourceType: ssm.ResourceTypeForTaggingParameter}
+
+	result, err := ps.Ssms.ListTagsForResourceRequest(&input).Send()
+	if err != nil {
+		return nil, err
+	}
+
+	return result.TagList, nil
+}
+
+func (ps SsmParameterStore) AddTags(name string, tags []ssm.Tag) error {
+	input := ssm.AddTagsToResourceInput{
+		ResourceId:   &name,
+		ResourceType: ssm.ResourceTypeForTaggingParameter,
+		Tags:         tags}
+
+	_, err := ps.Ssms.AddTagsToResourceRequest(&input).Send()
+	return err
+}
+
+func (ps SsmParameterStore) LabelParameterVersion(name string, version int64, labels []string) error {
+	input := ssm.LabelParameterVersionInput{
+		Name:   &name,
+		Labels: labels}
+	if version != 0 {
+		input.ParameterVersion = &version
+	}
+
+	result, err := ps.Ssms.LabelParameterVersionRequest(&input).Send()
+	if err != nil {
+		return err
+	}
+
+	if len(result.InvalidLabels) > 0 {
+		return errors.New("ValidationException: invalid labels " + strings.Join(result.InvalidLabels, ",") + ". name " + name)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io/ioutil"
+	"text/template"
+)
+
+// the suffix appended to the path of each -f file to find its template for render.
+const TemplateSuffix = ".tmpl"
+
+// Build the param, required, default and base64 functions available to a
+// template rendered with dict. default takes the fallback first, so that it may
+// be piped, like {{ param "port" | default "8080" }}.
+func renderFuncs(dict map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"param": func(key string) string {
+			return dict[key]
+		},
+		"required": func(key string) (string, error) {
+			value, ok := dict[key]
+			if !ok {
+				return "", errors.New("required parameter is missing. key " + key)
+			}
+			return value, nil
+		},
+		"default": func(fallback string, value string) string {
+			if len(value) == 0 {
+				return fallback
+			}
+			return value
+		},
+		"base64": func(value string) string {
+			return base64.StdEncoding.EncodeToString([]byte(value))
+		},
+	}
+}
+
+// Render the Go text/template at the path of the FileStore for filename plus
+// TemplateSuffix, with the parameters merged from each -s prefix like get, and
+// write the output to the path of the FileStore, like Save. The template data
+// is the merged dict, so values may also be read like {{ .host }}.
+func renderParamsPerFile(ctx *CmdContext, filename string) error {
+	store := ctx.Stores[filename]
+	if store.IsStream() {
+		return errors.New("render requires a template file, and does not support -f " + StreamFilename)
+	}
+
+	dict := make(map[string]string, 0)
+	paramTypes := make(map[string]ssm.ParameterType, 0)
+	if err := mergeParamsPerFile(ctx, filename, &dict, paramTypes); err != nil {
+		return err
+	}
+
+	templatePath := store.Path + TemplateSuffix
+	text, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New(templatePath).Funcs(renderFuncs(dict)).Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, dict); err != nil {
+		return err
+	}
+
+	store.Dict = dict
+	store.Secure = hasSecureStringType(paramTypes)
+	return store.write(buf.Bytes())
+}
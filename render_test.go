@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf", "/ep/conf/prod"}, "nginx.conf")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/nginx/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/nginx/host", "prod.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/nginx/ssl.key", "secret", ssm.ParameterTypeSecureString)
+
+	path := filepath.Join(ctx.Prefs.ConfDir, "nginx.conf")
+	ioutil.WriteFile(path+TemplateSuffix, []byte(
+		`server_name {{ .host }}; listen {{ param "port" | default "80" }}; key {{ required "ssl.key" | base64 }};`), 0600)
+
+	if err := renderParamsPerFile(ctx, "nginx.conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	expected := "server_name prod.example.com; listen 80; key c2VjcmV0;"
+	if string(data) != expected {
+		t.Errorf("unexpected render. expected: %s, actual: %s\n", expected, string(data))
+	}
+	assertFileMode(t, path, 0600)
+
+	ioutil.WriteFile(path+TemplateSuffix, []byte(`{{ required "missing" }}`), 0600)
+	if err := renderParamsPerFile(ctx, "nginx.conf"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected an error for a missing required parameter. actual: %v\n", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != expected {
+		t.Errorf("expected a failed render to leave the file unchanged. actual: %s\n", string(data))
+	}
+}
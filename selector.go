@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the separator between a parameter name or -s prefix and a selector, which
+// picks a version of each parameter by number or by label, like
+// /ep/conf/ecs/host:3 or /ep/conf:release-42.
+const SelectorSeparator = ":"
+
+// the maximum number of labels SSM allows on a single parameter version.
+const maxLabelsPerVersion = 10
+
+var labelPattern = regexp.MustCompile(`^[a-zA-Z0-9_.\-]{1,100}$`)
+
+// Split a trailing selector from a parameter name or -s prefix. The selector
+// is empty if there is none.
+func splitSelector(arg string) (string, string) {
+	if i := strings.LastIndex(arg, SelectorSeparator); i >= 0 {
+		return arg[:i], arg[i+len(SelectorSeparator):]
+	}
+	return arg, ""
+}
+
+// Return the version number picked by selector, or false if the selector is
+// a label.
+func selectorVersion(selector string) (int64, bool) {
+	version, err := strconv.ParseInt(selector, 10, 64)
+	return version, err == nil
+}
+
+// Return true if label follows the SSM rules for parameter labels, which may
+// not begin with a number, "aws" or "ssm".
+func isValidLabel(label string) bool {
+	lower := strings.ToLower(label)
+	return labelPattern.MatchString(label) && !(label[0] >= '0' && label[0] <= '9') &&
+		!strings.HasPrefix(lower, "aws") && !strings.HasPrefix(lower, "ssm")
+}
+
+// Return an error unless selector is a positive version number or a valid
+// label.
+func validateSelector(selector string) error {
+	if version, ok := selectorVersion(selector); ok {
+		if version <= 0 {
+			return errors.New("version selectors must be positive. selector " + selector)
+		}
+		return nil
+	}
+	if !isValidLabel(selector) {
+		return errors.New("invalid label selector " + selector)
+	}
+	return nil
+}
+
+// Read the name:selector pins of a lock file, one per line, keyed by
+// parameter name. Blank lines and lines beginning with # are ignored.
+func readLockFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	locks := make(map[string]string, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, selector := splitSelector(line)
+		if !strings.HasPrefix(name, "/") || len(selector) == 0 {
+			return nil, fmt.Errorf("expected /name:selector at line %d of %s", lineNo, path)
+		}
+		if err := validateSelector(selector); err != nil {
+			return nil, fmt.Errorf("%s at line %d of %s", err, lineNo, path)
+		}
+		locks[name] = selector
+	}
+
+	return locks, scanner.Err()
+}
+
+// Return the selector declared with the -s prefix at index i, if any.
+func prefixSelectorAt(ctx *CmdContext, i int) string {
+	if i < len(ctx.Prefs.Selectors) {
+		return ctx.Prefs.Selectors[i]
+	}
+	return ""
+}
+
+// Replace each listed parameter with the version picked by its selector,
+// which is the selector pinned for its name in the --lock file, or else the
+// selector of its -s prefix. Parameters without a version matching the
+// selector of their prefix are dropped, but a pinned version that cannot be
+// found is an error.
+func selectParams(ctx *CmdContext, params []ssm.Parameter, prefixSelector string) ([]ssm.Parameter, error) {
+	selected := make([]ssm.Parameter, 0, len(params))
+	var selectorNames []string
+	for _, param := range params {
+		selector := prefixSelector
+		if pinned, ok := ctx.Prefs.Locks[*param.Name]; ok {
+			selector = pinned
+		}
+
+		if len(selector) == 0 {
+			selected = append(selected, param)
+		} else {
+			selectorNames = append(selectorNames, *param.Name+SelectorSeparator+selector)
+		}
+	}
+
+	if len(selectorNames) == 0 {
+		return selected, nil
+	}
+
+	resolved, invalid, err := ctx.Params.GetParameters(selectorNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, selectorName := range invalid {
+		name, _ := splitSelector(selectorName)
+		if _, pinned := ctx.Prefs.Locks[name]; pinned {
+			return nil, errors.New("pinned parameter version not found. selector " + selectorName)
+		}
+	}
+
+	return append(selected, resolved...), nil
+}
+
+// Attach each --label to the selected version of every parameter for
+// filename, from each -s prefix, and write a line for each labeled parameter
+// to w.
+func labelParamsPerFile(ctx *CmdContext, filename string, w io.Writer) error {
+	for i, prefix := range ctx.Prefs.Prefixes {
+		params, err := findAllParametersForPath(ctx, buildParameterPath(prefix, filename, ""), isRecursiveFor(ctx, filename))
+		if err != nil {
+			return err
+		}
+
+		params, err = selectParams(ctx, params, prefixSelectorAt(ctx, i))
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(params, func(a, b int) bool { return *params[a].Name < *params[b].Name })
+		for _, param := range params {
+			if err := ctx.Params.LabelParameterVersion(*param.Name, *param.Version, ctx.Prefs.Labels); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "labeled %s v%d with %s\n", *param.Name, *param.Version, strings.Join(ctx.Prefs.Labels, ","))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSelector(t *testing.T) {
+	expectations := map[string][]string{
+		"/ep/conf":            {"/ep/conf", ""},
+		"/ep/conf:release-42": {"/ep/conf", "release-42"},
+		"/ep/conf/ecs/host:3": {"/ep/conf/ecs/host", "3"}}
+
+	for arg, expected := range expectations {
+		name, selector := splitSelector(arg)
+		if !reflect.DeepEqual([]string{name, selector}, expected) {
+			t.Errorf("unexpected split of %s: %s, %s\n", arg, name, selector)
+		}
+	}
+
+	for _, selector := range []string{"3", "release-42", "prod_v1.2"} {
+		if err := validateSelector(selector); err != nil {
+			t.Errorf("expected selector %s to be valid. reason: %s\n", selector, err)
+		}
+	}
+	for _, selector := range []string{"0", "-1", "1release", "aws-prod", "SSM", "release 42"} {
+		if err := validateSelector(selector); err == nil {
+			t.Errorf("expected selector %s to be invalid\n", selector)
+		}
+	}
+}
+
+func TestReadLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lockFile := filepath.Join(dir, "ssmple.lock")
+	data := "# pinned for release 42\n/ep/conf/ecs/host:3\n\n/ep/conf/ecs/port:release-42\n"
+	if err := ioutil.WriteFile(lockFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	locks, err := readLockFile(lockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, locks, map[string]string{
+		"/ep/conf/ecs/host": "3",
+		"/ep/conf/ecs/port": "release-42"})
+
+	if err := ioutil.WriteFile(lockFile, []byte("/ep/conf/ecs/host\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readLockFile(lockFile); err == nil {
+		t.Errorf("expected an error for a line without a selector\n")
+	}
+}
+
+func TestLabelAndGetSelectedParams(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "one.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/port", "8080", ssm.ParameterTypeString)
+
+	ctx.Prefs.Labels = []string{"release-42"}
+	out := bytes.Buffer{}
+	if err := labelParamsPerFile(ctx, "ecs.properties", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "labeled /ep/conf/ecs/host v1 with release-42") {
+		t.Errorf("expected each labeled parameter to be printed. actual:\n%s\n", out.String())
+	}
+
+	overwriteTestParam(t, ctx, "/ep/conf/ecs/host", "two.example.com")
+	putTestParam(t, ctx, "/ep/conf/ecs/timeout", "30", ssm.ParameterTypeString)
+
+	ctx.Prefs.Selectors = []string{"release-42"}
+	dict := make(map[string]string, 0)
+	if err := mergeParamsPerFile(ctx, "ecs.properties", &dict, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, dict, map[string]string{"host": "one.example.com", "port": "8080"})
+
+	ctx.Prefs.Selectors = nil
+	ctx.Prefs.Locks = map[string]string{"/ep/conf/ecs/host": "1"}
+	dict = make(map[string]string, 0)
+	if err := mergeParamsPerFile(ctx, "ecs.properties", &dict, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, dict, map[string]string{"host": "one.example.com", "port": "8080", "timeout": "30"})
+
+	ctx.Prefs.Locks = map[string]string{"/ep/conf/ecs/host": "missing"}
+	if err := mergeParamsPerFile(ctx, "ecs.properties", &dict, nil); err == nil {
+		t.Errorf("expected an error for a pinned version that does not exist\n")
+	}
+}
+
+func TestLabelParameterVersionMovesLabel(t *testing.T) {
+	ps := NewMemParameterStore()
+	name := "/ep/conf/ecs/host"
+	for _, value := range []string{"one", "two"} {
+		overwrite := true
+		input := ssm.PutParameterInput{Name: &name, Value: &value, Type: ssm.ParameterTypeString, Overwrite: &overwrite}
+		if err := ps.PutParameter(&input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ps.LabelParameterVersion(name, 1, []string{"prod", "release-41"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.LabelParameterVersion(name, 0, []string{"prod"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := ps.GetParameterHistory(name)
+	if !reflect.DeepEqual(history[0].Labels, []string{"release-41"}) || !reflect.DeepEqual(history[1].Labels, []string{"prod"}) {
+		t.Errorf("expected the prod label to move to the latest version. actual: %v, %v\n", history[0].Labels, history[1].Labels)
+	}
+
+	params, invalid, _ := ps.GetParameters([]string{name + ":prod", name + ":1", name + ":3"})
+	if len(params) != 2 || *params[0].Value != "two" || *params[1].Value != "one" || *params[0].Name != name {
+		t.Errorf("unexpected selected parameters: %v\n", params)
+	}
+	if !reflect.DeepEqual(invalid, []string{name + ":3"}) {
+		t.Errorf("expected the missing version to be invalid. actual: %v\n", invalid)
+	}
+
+	if err := ps.LabelParameterVersion(name, 0, []string{"aws-prod"}); err == nil {
+		t.Errorf("expected an error for a reserved label prefix\n")
+	}
+	if err := ps.LabelParameterVersion(name, 3, []string{"prod"}); err == nil {
+		t.Errorf("expected an error for a missing version\n")
+	}
+}
@@ -17,33 +17,221 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"github.com/rickar/props"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // simple serializer interface for load/save operations between maps
-// and different file formats.
+// and different file formats. A Serial reads and writes streams, so that the
+// FileStore may use files, stdin or stdout.
 type Serial interface {
-	Load(path string) (map[string]string, error)
-	Save(path string, dict *map[string]string) error
+	Load(r io.Reader) (map[string]string, error)
+	Save(w io.Writer, dict *map[string]string) error
 }
 
-// the default Serial implementation writes to files in Java .properties format,
-// which can also support OSGi configs and shell variable declaration files.
-type PropsSerial struct{}
+// a Serial that implements LayoutSerial keeps the layout of the previous
+// document, like comments and key order, when saving over an existing file.
+// The previous reader is nil when there is no existing file.
+type LayoutSerial interface {
+	Serial
+	SaveOver(w io.Writer, previous io.Reader, dict *map[string]string) error
+}
+
+// options for reading and writing files that only apply to some formats, set
+// by the --comment-removed, --shell-keys, --k8s-name and --k8s-namespace flags.
+type SerialOptions struct {
+	// true to comment out the lines of keys removed from a properties file on
+	// save, instead of dropping them
+	CommentRemoved bool
+
+	// how keys are turned into variable names in .sh files, or empty for
+	// ShellKeysUnderscore
+	ShellKeys string
+
+	// the manifest name of .k8s.yaml files, or empty to derive it from the
+	// filename
+	K8sName string
+
+	// the manifest namespace of .k8s.yaml files
+	K8sNamespace string
+
+	// true to infer the types of new values in .toml files from their string
+	// form, instead of saving them as strings
+	TomlInferTypes bool
+
+	// the <comment> of .xml files, or empty to keep the comment of the
+	// existing file
+	XmlComment string
+}
+
+// a Serial that implements ConfigurableSerial derives part of its output from
+// the filename or the SerialOptions of the FileStore, like the K8sSerial
+// deriving a manifest name. Configure returns a Serial for the named file.
+type ConfigurableSerial interface {
+	Serial
+	Configure(name string, options SerialOptions) Serial
+}
 
-// Read a key-value map from a file specified by path.
-func (s PropsSerial) Load(path string) (map[string]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
+// a Serial that implements NestedSerial reads and writes nested documents. Its
+// dict keys are the paths to each scalar value in the document, joined by
+// NestedKeySeparator, so that they map onto SSM parameter sub-paths.
+type NestedSerial interface {
+	Serial
+	IsNested() bool
+}
+
+const NestedKeySeparator = "/"
+
+// a Serial that implements SecureSerial stores SecureString values differently
+// from other values. It relies on the _SecureStringKeyId sidecar of each
+// SecureString, which is then retrieved on get even without --get-key-id.
+type SecureSerial interface {
+	Serial
+	NeedsKeyId() bool
+}
+
+// Flatten a decoded document into a dict, joining the keys of nested maps with
+// NestedKeySeparator. Scalar values are formatted as strings, and arrays of
+// scalars are joined by commas and marked as StringLists.
+func flattenDoc(doc map[string]interface{}) (map[string]string, error) {
+	dict := make(map[string]string)
+	if err := flattenInto(dict, "", doc); err != nil {
 		return nil, err
 	}
+	return dict, nil
+}
+
+func flattenInto(dict map[string]string, keyPrefix string, doc map[string]interface{}) error {
+	for k, v := range doc {
+		key := keyPrefix + k
+		switch v.(type) {
+		case string:
+			dict[key] = v.(string)
+		case map[string]interface{}:
+			if err := flattenInto(dict, key+NestedKeySeparator, v.(map[string]interface{})); err != nil {
+				return err
+			}
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(v.(map[interface{}]interface{})))
+			for mk, mv := range v.(map[interface{}]interface{}) {
+				m[fmt.Sprintf("%v", mk)] = mv
+			}
+			if err := flattenInto(dict, key+NestedKeySeparator, m); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, len(v.([]interface{})))
+			for i, item := range v.([]interface{}) {
+				switch item.(type) {
+				case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+					return errors.New("arrays may only contain scalar values. key " + key)
+				}
+				items[i] = fmt.Sprintf("%v", item)
+				if strings.ContainsRune(items[i], ',') {
+					return errors.New("array values must not contain commas. key " + key)
+				}
+			}
+			dict[key] = strings.Join(items, ",")
+			dict[key+StringListSuffix] = "true"
+		case []map[string]interface{}:
+			return errors.New("arrays of objects are not supported. key " + key)
+		case time.Time:
+			dict[key] = v.(time.Time).Format(time.RFC3339Nano)
+		case nil:
+			dict[key] = ""
+		default:
+			dict[key] = fmt.Sprintf("%v", v)
+		}
+	}
+	return nil
+}
+
+// Rebuild a nested document from a dict whose keys were joined by
+// NestedKeySeparator. StringList values become arrays. SSM allows a parameter
+// like /a/x alongside /a/x/y, which a document can not hold, so the value of
+// the shorter key is kept and the keys nested below it are skipped with a
+// warning.
+func nestDict(dict map[string]string) map[string]interface{} {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		if strings.HasSuffix(key, StringListSuffix) {
+			if _, hasBuddy := dict[strings.TrimSuffix(key, StringListSuffix)]; hasBuddy {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	doc := make(map[string]interface{})
+	for _, key := range keys {
+		segments := strings.Split(key, NestedKeySeparator)
+		parent := doc
+		conflict := ""
+		for i, segment := range segments[:len(segments)-1] {
+			child, exists := parent[segment]
+			if !exists {
+				child = make(map[string]interface{})
+				parent[segment] = child
+			}
+			childMap, isMap := child.(map[string]interface{})
+			if !isMap {
+				conflict = strings.Join(segments[:i+1], NestedKeySeparator)
+				break
+			}
+			parent = childMap
+		}
+
+		leaf := segments[len(segments)-1]
+		if _, exists := parent[leaf]; exists && len(conflict) == 0 {
+			conflict = key
+		}
+		if len(conflict) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipped key %s, which is nested below the value of key %s\n", key, conflict)
+			continue
+		}
+		if isStringListKey(dict, key) {
+			items := []string{}
+			if len(dict[key]) > 0 {
+				items = strings.Split(dict[key], ",")
+			}
+			parent[leaf] = items
+		} else {
+			parent[leaf] = dict[key]
+		}
+	}
 
+	return doc
+}
+
+// the default Serial implementation writes to files in Java .properties format,
+// which can also support OSGi configs. Shell variable declaration files should
+// use the .sh extension, which selects the ShellSerial.
+type PropsSerial struct {
+	// true to comment out the lines of removed keys, instead of dropping them
+	CommentRemoved bool
+}
+
+func (s PropsSerial) Configure(name string, options SerialOptions) Serial {
+	return PropsSerial{CommentRemoved: options.CommentRemoved}
+}
+
+// Read a key-value map in properties format.
+func (s PropsSerial) Load(r io.Reader) (map[string]string, error) {
 	p := props.NewProperties()
-	p.Load(file)
+	if err := p.Load(r); err != nil {
+		return nil, err
+	}
 
 	dict := make(map[string]string, len(p.Names()))
 	names := p.Names()
@@ -55,28 +243,143 @@ func (s PropsSerial) Load(path string) (map[string]string, error) {
 	return dict, nil
 }
 
-// Write a key-value map to a file specified by path.
-func (s PropsSerial) Save(path string, dict *map[string]string) error {
-	p := props.NewProperties()
-	for key, value := range *dict {
-		p.Set(key, value)
+// Write a key-value map in properties format, with keys in sorted order.
+func (s PropsSerial) Save(w io.Writer, dict *map[string]string) error {
+	return s.SaveOver(w, nil, dict)
+}
+
+// Write a key-value map in properties format, keeping the comments, blank
+// lines and key order of the previous document: values are updated in place,
+// new keys are appended in sorted order, and removed keys are dropped, or
+// commented out when CommentRemoved is set.
+func (s PropsSerial) SaveOver(w io.Writer, previous io.Reader, dict *map[string]string) error {
+	var lines []string
+	if previous != nil {
+		data, err := ioutil.ReadAll(previous)
+		if err != nil {
+			return err
+		}
+		lines = splitPropsLines(string(data))
+	}
+
+	out := make([]string, 0, len(lines)+len(*dict))
+	written := make(map[string]bool, len(*dict))
+	for _, line := range lines {
+		key, value, isEntry := parsePropsEntry(line)
+		if !isEntry {
+			out = append(out, line)
+			continue
+		}
+
+		if newValue, ok := (*dict)[key]; ok {
+			if newValue == value {
+				out = append(out, line)
+			} else {
+				out = append(out, formatPropsEntry(key, newValue))
+			}
+			written[key] = true
+		} else if s.CommentRemoved {
+			for _, physical := range strings.Split(line, "\n") {
+				out = append(out, "# "+physical)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(out, formatPropsEntry(key, (*dict)[key]))
 	}
 
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	bw := bufio.NewWriter(w)
+	for _, line := range out {
+		bw.WriteString(line + "\n")
 	}
 
-	return p.Write(file)
+	return bw.Flush()
+}
+
+// Split properties file data into logical lines, joining each line that ends
+// in an unescaped backslash with the physical lines that continue it.
+func splitPropsLines(data string) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	physical := strings.Split(strings.TrimSuffix(strings.Replace(data, "\r\n", "\n", -1), "\n"), "\n")
+	lines := make([]string, 0, len(physical))
+	for i := 0; i < len(physical); i++ {
+		line := physical[i]
+		if !isPropsComment(line) {
+			for continuesPropsLine(physical[i]) && i+1 < len(physical) {
+				i++
+				line += "\n" + physical[i]
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isPropsComment(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t\f")
+	return len(trimmed) == 0 || trimmed[0] == '#' || trimmed[0] == '!'
+}
+
+// a line continues onto the next if it ends in an odd number of backslashes.
+func continuesPropsLine(line string) bool {
+	trailing := len(line) - len(strings.TrimRight(line, "\\"))
+	return trailing%2 == 1
+}
+
+// Parse the key and value of a logical line. Returns false for comments and
+// blank lines.
+func parsePropsEntry(line string) (string, string, bool) {
+	if isPropsComment(line) {
+		return "", "", false
+	}
+
+	p := props.NewProperties()
+	p.Load(strings.NewReader(line))
+	names := p.Names()
+	if len(names) != 1 {
+		return "", "", false
+	}
+	return names[0], p.Get(names[0]), true
+}
+
+// Format a single key=value line, escaped as the props library writes it.
+func formatPropsEntry(key string, value string) string {
+	p := props.NewProperties()
+	p.Set(key, value)
+	buf := bytes.Buffer{}
+	p.Write(&buf)
+	return strings.TrimSuffix(buf.String(), "\n")
 }
 
 // private global map of file extensions to Serial implementations.
 var serials = make(map[string]Serial, 0)
 
+// Return the extension of path, preferring a registered compound extension like
+// .k8s.yaml over the last extension.
+func serialExt(path string) string {
+	ext := filepath.Ext(path)
+	if inner := filepath.Ext(strings.TrimSuffix(path, ext)); len(inner) > 0 {
+		if _, ok := serials[inner+ext]; ok {
+			return inner + ext
+		}
+	}
+	return ext
+}
+
 // Retrieve the appropriate serializer for the given path.
 // Returns the PropsSerial by default for unregistered extensions.
 func GetSerialFor(path string) Serial {
-	ext := filepath.Ext(path)
+	ext := serialExt(path)
 	serial := serials[""]
 	if extSerial, ok := serials[ext]; ok {
 		serial = extSerial
@@ -84,8 +387,32 @@ func GetSerialFor(path string) Serial {
 	return serial
 }
 
+// Retrieve the serializer for a format name, which is a registered extension
+// without its leading period, like yaml or k8s.yaml. Returns false if no
+// serializer is registered for the format.
+func GetSerialForFormat(format string) (Serial, bool) {
+	if len(format) == 0 || strings.HasPrefix(format, ".") {
+		return nil, false
+	}
+	serial, ok := serials["."+format]
+	return serial, ok
+}
+
+// Split a filename argument like app.conf:yaml into the filename and an
+// explicit format name. The suffix is only treated as a format if a serializer
+// is registered for it, otherwise the whole argument is the filename.
+func splitFormatSuffix(arg string) (string, string) {
+	if i := strings.LastIndex(arg, ":"); i > 0 {
+		if _, ok := GetSerialForFormat(arg[i+1:]); ok {
+			return arg[:i], arg[i+1:]
+		}
+	}
+	return arg, ""
+}
+
 // Register a serializer implementation for one or more extensions.
-// Each provided value in exts must begin with a period. An error will
+// Each provided value in exts must begin with a period, and may be a
+// compound extension like .k8s.yaml. An error will
 // be thrown if an attempt is made to register for an extension that has
 // already been registered.
 func RegisterSerial(serial Serial, exts ...string) error {
@@ -102,7 +429,8 @@ func RegisterSerial(serial Serial, exts ...string) error {
 	return nil
 }
 
-// add the default PropsSerial impl
+// add the default PropsSerial impl, also registered as the properties format
 func init() {
 	serials[""] = PropsSerial{}
+	RegisterSerial(PropsSerial{}, ".properties")
 }
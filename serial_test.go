@@ -17,6 +17,12 @@
 package main
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -44,4 +50,558 @@ func TestGetSerialFor(t *testing.T) {
 	default:
 		t.Error("should be a JsonSerial!")
 	}
+
+	switch GetSerialFor("test.env").(type) {
+	case DotenvSerial: // good
+	default:
+		t.Error("should be a DotenvSerial!")
+	}
+
+	switch GetSerialFor("test.sh").(type) {
+	case ShellSerial: // good
+	default:
+		t.Error("should be a ShellSerial!")
+	}
+
+	switch GetSerialFor("test.toml").(type) {
+	case TomlSerial: // good
+	default:
+		t.Error("should be a TomlSerial!")
+	}
+
+	switch GetSerialFor("test.cfg").(type) {
+	case IniSerial: // good
+	default:
+		t.Error("should be an IniSerial!")
+	}
+
+	switch GetSerialFor("test.xml").(type) {
+	case XmlPropsSerial: // good
+	default:
+		t.Error("should be an XmlPropsSerial!")
+	}
+
+	switch GetSerialFor("test.k8s.yaml").(type) {
+	case K8sSerial: // good
+	default:
+		t.Error("should be a K8sSerial!")
+	}
+}
+
+func assertSerialRoundTrip(t *testing.T, filename string, content string, expected map[string]string) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileStore(dir, filename)
+	if err := fs.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fs.Dict, expected) {
+		t.Errorf("%s did not load as expected. expected: %v, actual: %v\n", filename, expected, fs.Dict)
+	}
+
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewFileStore(dir, filename)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Dict, expected) {
+		t.Errorf("%s did not round trip. expected: %v, actual: %v\n", filename, expected, reloaded.Dict)
+	}
+}
+
+func TestNestedYamlRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "application.yml", `
+server:
+  port: 8080
+spring:
+  datasource:
+    url: jdbc:h2:mem
+    password: secret
+name: app
+`, map[string]string{
+		"server/port":                "8080",
+		"spring/datasource/url":      "jdbc:h2:mem",
+		"spring/datasource/password": "secret",
+		"name":                       "app"})
+}
+
+func TestNestedJsonRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "application.json",
+		`{"server": {"port": 8080, "timeout": 12345678, "ratio": 1.50}, "spring": {"datasource": {"url": "jdbc:h2:mem"}},
+		"ids": [12345678, 9007199254740993], "name": "app"}`,
+		map[string]string{
+			"server/port":            "8080",
+			"server/timeout":         "12345678",
+			"server/ratio":           "1.50",
+			"spring/datasource/url":  "jdbc:h2:mem",
+			"ids":                    "12345678,9007199254740993",
+			"ids" + StringListSuffix: "true",
+			"name":                   "app"})
+}
+
+func TestNestDictConflict(t *testing.T) {
+	doc := nestDict(map[string]string{"a": "value", "a/b": "nested", "a/b/c": "deeper", "d/e": "kept"})
+	expected := map[string]interface{}{"a": "value", "d": map[string]interface{}{"e": "kept"}}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected keys nested below a value to be skipped. actual: %v\n", doc)
+	}
+}
+
+func TestStringListYamlRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "application.yml", `
+hosts:
+- alpha
+- beta
+ports: [80, 443]
+`, map[string]string{
+		"hosts":                    "alpha,beta",
+		"hosts" + StringListSuffix: "true",
+		"ports":                    "80,443",
+		"ports" + StringListSuffix: "true"})
+}
+
+func TestParseDotenv(t *testing.T) {
+	dict, err := parseDotenv(`# a comment
+export EXPORTED=yes
+PLAIN=value # inline comment
+SINGLE='it is $HOME'
+DOUBLE="say \"hi\"\nbye"
+MULTI="line one
+line two"
+EMPTY=
+
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"EXPORTED": "yes",
+		"PLAIN":    "value",
+		"SINGLE":   "it is $HOME",
+		"DOUBLE":   "say \"hi\"\nbye",
+		"MULTI":    "line one\nline two",
+		"EMPTY":    ""}
+	if !reflect.DeepEqual(dict, expected) {
+		t.Errorf(".env did not parse as expected. expected: %v, actual: %v\n", expected, dict)
+	}
+
+	if _, err := parseDotenv("UNTERMINATED=\"value\n"); err == nil {
+		t.Error("expected an error for an unterminated quoted value")
+	}
+}
+
+func TestDotenvRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "app.env", `
+URL=jdbc:h2:mem
+GREETING='hello world'
+PRICE='costs $5'
+QUOTE="it's"
+MULTI="one
+two"
+`, map[string]string{
+		"URL":      "jdbc:h2:mem",
+		"GREETING": "hello world",
+		"PRICE":    "costs $5",
+		"QUOTE":    "it's",
+		"MULTI":    "one\ntwo"})
+}
+
+func TestShellRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "app.sh", `
+# sourced by the entrypoint
+export PLAIN='value'
+export QUOTED='it'\''s $HOME'
+export db_url='jdbc:h2:mem' # key: db.url
+MIXED=bare"double \$ quoted"'single'
+export MULTI='one
+two'
+`, map[string]string{
+		"PLAIN":  "value",
+		"QUOTED": "it's $HOME",
+		"db.url": "jdbc:h2:mem",
+		"MIXED":  "baredouble $ quotedsingle",
+		"MULTI":  "one\ntwo"})
+}
+
+func TestShellVarName(t *testing.T) {
+	if name, _ := shellVarName("spring.datasource-url", ShellKeysUnderscore); name != "spring_datasource_url" {
+		t.Errorf("unexpected underscore name %s\n", name)
+	}
+	if name, _ := shellVarName("9lives", ShellKeysUpper); name != "_9LIVES" {
+		t.Errorf("unexpected upper name %s\n", name)
+	}
+	if _, err := shellVarName("db.url", ShellKeysStrict); err == nil {
+		t.Error("expected an error for an invalid key in strict mode")
+	}
+}
+
+func TestTomlRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "service.toml", `
+name = "app"
+version = "007"
+debug = true
+
+[server]
+port = 8080
+ratio = 1.0
+started = 2018-06-01T12:00:00Z
+hosts = ["alpha", "beta"]
+
+[server.tls]
+enabled = false
+`, map[string]string{
+		"name":                            "app",
+		"version":                         "007",
+		"debug":                           "true",
+		"server/port":                     "8080",
+		"server/ratio":                    "1.0",
+		"server/started":                  "2018-06-01T12:00:00Z",
+		"server/hosts":                    "alpha,beta",
+		"server/hosts" + StringListSuffix: "true",
+		"server/tls/enabled":              "false"})
+}
+
+func TestTomlKeepsStrings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "service.toml")
+	ioutil.WriteFile(path, []byte(`
+version = "1.0"
+flag = "true"
+port = 8080
+hosts = ["80", "443"]
+
+[server]
+port = "8080"
+`), 0600)
+
+	fs := NewFileStore(dir, "service.toml")
+	if err := fs.Load(); err != nil {
+		t.Fatal(err)
+	}
+	fs.Dict["added"] = "42"
+	fs.SerialOptions.TomlInferTypes = true
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`version = "1.0"`, `flag = "true"`, `port = 8080`, `hosts = ["80", "443"]`,
+		`port = "8080"`, `added = 42`} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("expected saved file to contain %s. actual:\n%s\n", line, string(data))
+		}
+	}
+}
+
+func TestTomlFreshFileStrings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, infer := range []bool{false, true} {
+		fs := NewFileStore(dir, "fresh.toml")
+		fs.Dict["port"] = "8080"
+		fs.Dict["debug"] = "true"
+		fs.SerialOptions.TomlInferTypes = infer
+		if err := fs.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile(fs.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{`port = "8080"`, `debug = "true"`}
+		if infer {
+			expected = []string{`port = 8080`, `debug = true`}
+		}
+		for _, line := range expected {
+			if !strings.Contains(string(data), line) {
+				t.Errorf("expected saved file to contain %s with inference %t. actual:\n%s\n", line, infer, string(data))
+			}
+		}
+		os.Remove(fs.Path)
+	}
+}
+
+func TestIniRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "uwsgi.ini", `
+; global settings
+name = app
+
+[uwsgi]
+socket = /tmp/uwsgi.sock
+processes = 4
+
+[PHP]
+memory_limit = 128M
+`, map[string]string{
+		"name":             "app",
+		"uwsgi/socket":     "/tmp/uwsgi.sock",
+		"uwsgi/processes":  "4",
+		"PHP/memory_limit": "128M"})
+}
+
+func TestXmlPropsRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "legacy.xml", `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE properties SYSTEM "http://java.sun.com/dtd/properties.dtd">
+<properties>
+<comment>managed by ssmple</comment>
+<entry key="jdbc.url">jdbc:h2:mem</entry>
+<entry key="greeting">a &lt;b&gt; &amp; c</entry>
+</properties>
+`, map[string]string{
+		"jdbc.url": "jdbc:h2:mem",
+		"greeting": "a <b> & c"})
+}
+
+func TestXmlPropsKeepsComment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "legacy.xml")
+	ioutil.WriteFile(path, []byte(`<properties><comment>keep me</comment></properties>`), 0600)
+
+	fs := NewFileStore(dir, "legacy.xml")
+	fs.Dict = map[string]string{"key": "value"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := readXmlProps(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Comment == nil || *doc.Comment != "keep me" {
+		t.Errorf("expected the comment to be kept. actual: %v\n", doc.Comment)
+	}
+}
+
+func TestXmlPropsNewComment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := NewFileStore(dir, "fresh.xml")
+	fs.Dict = map[string]string{"key": "value"}
+	fs.SerialOptions.XmlComment = "written by ssmple"
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(fs.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := readXmlProps(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Comment == nil || *doc.Comment != "written by ssmple" {
+		t.Errorf("expected the comment to be set on a new file. actual: %v\n", doc.Comment)
+	}
+}
+
+func TestK8sRoundTrip(t *testing.T) {
+	assertSerialRoundTrip(t, "my_app.k8s.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app
+  annotations:
+    ssmple/hosts_StringList: "true"
+data:
+  db.url: jdbc:h2:mem
+  hosts: a,b
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app
+  annotations:
+    ssmple/api.key_SecureStringKeyId: alias/app
+type: Opaque
+data:
+  password: c2VjcmV0
+  api.key: a2V5
+`, map[string]string{
+		"db.url":                     "jdbc:h2:mem",
+		"hosts":                      "a,b",
+		"hosts_StringList":           "true",
+		"password":                   "secret",
+		"password_SecureStringKeyId": "",
+		"api.key":                    "key",
+		"api.key_SecureStringKeyId":  "alias/app"})
+
+	if name := k8sNameFor("/conf/my_app.k8s.yaml"); name != "my-app" {
+		t.Errorf("unexpected manifest name %s\n", name)
+	}
+}
+
+func TestPropsKeepsLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.properties")
+	ioutil.WriteFile(path, []byte(`# database
+db.url = jdbc:h2:mem
+
+! kept as written
+db.user : sa
+db.hosts = a,\
+    b
+removed=gone
+`), 0600)
+
+	dict := map[string]string{
+		"db.url":   "jdbc:postgresql://db",
+		"db.user":  "sa",
+		"db.hosts": "a,b",
+		"added":    "new"}
+
+	fs := NewFileStore(dir, "app.properties")
+	fs.SerialOptions.CommentRemoved = true
+	fs.Dict = dict
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	expected := `# database
+db.url=jdbc\:postgresql\://db
+
+! kept as written
+db.user : sa
+db.hosts = a,\
+    b
+# removed=gone
+added=new
+`
+	if string(data) != expected {
+		t.Errorf("properties layout was not kept. expected:\n%s\nactual:\n%s\n", expected, string(data))
+	}
+
+	reloaded := NewFileStore(dir, "app.properties")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Dict, dict) {
+		t.Errorf("properties did not reload as expected. expected: %v, actual: %v\n", dict, reloaded.Dict)
+	}
+}
+
+func TestSplitFormatSuffix(t *testing.T) {
+	if filename, format := splitFormatSuffix("application.conf:yaml"); filename != "application.conf" || format != "yaml" {
+		t.Errorf("unexpected split %s, %s\n", filename, format)
+	}
+	if filename, format := splitFormatSuffix("app:config"); filename != "app:config" || format != "" {
+		t.Errorf("unexpected split of unknown format %s, %s\n", filename, format)
+	}
+	if _, ok := GetSerialForFormat("properties"); !ok {
+		t.Error("expected a serial for the properties format")
+	}
+}
+
+func TestFileStoreFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "application.conf"), []byte("server:\n  port: 8080\n"), 0600)
+
+	fs := NewFileStore(dir, "application.conf")
+	fs.Format = "yaml"
+	if err := fs.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !fs.IsNested() {
+		t.Error("expected the yaml format to be nested")
+	}
+	expected := map[string]string{"server/port": "8080"}
+	if !reflect.DeepEqual(fs.Dict, expected) {
+		t.Errorf("application.conf did not load as yaml. expected: %v, actual: %v\n", expected, fs.Dict)
+	}
+}
+
+func TestStreamFileStore(t *testing.T) {
+	in := strings.NewReader("server:\n  port: 8080\n")
+	fs := NewStreamFileStore("app", "yaml", in, nil)
+	if err := fs.Load(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"server/port": "8080"}
+	if !reflect.DeepEqual(fs.Dict, expected) {
+		t.Errorf("stdin did not load as expected. expected: %v, actual: %v\n", expected, fs.Dict)
+	}
+
+	out := bytes.Buffer{}
+	fs = NewStreamFileStore("my_app", "k8s.yaml", nil, &out)
+	fs.Dict = map[string]string{"port": "8080"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "name: my-app") {
+		t.Errorf("expected the manifest name to derive from the stream name. actual:\n%s\n", out.String())
+	}
+}
+
+func TestFileStoreSerialOptions(t *testing.T) {
+	out := bytes.Buffer{}
+	fs := NewStreamFileStore("my_app", "k8s.yaml", nil, &out)
+	fs.SerialOptions = SerialOptions{K8sName: "web", K8sNamespace: "prod"}
+	fs.Dict = map[string]string{"port": "8080"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "name: web") || !strings.Contains(out.String(), "namespace: prod") {
+		t.Errorf("expected the manifest name and namespace from the options. actual:\n%s\n", out.String())
+	}
+
+	out.Reset()
+	fs = NewStreamFileStore("app", "sh", nil, &out)
+	fs.SerialOptions = SerialOptions{ShellKeys: ShellKeysUpper}
+	fs.Dict = map[string]string{"db.url": "jdbc:h2:mem"}
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "export DB_URL=") {
+		t.Errorf("expected upper case variable names from the options. actual:\n%s\n", out.String())
+	}
 }
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// modes for turning dict keys into shell variable names.
+const (
+	// replace each character that is invalid in a shell identifier with '_'
+	ShellKeysUnderscore = "underscore"
+
+	// like underscore, and also convert to upper case
+	ShellKeysUpper = "upper"
+
+	// fail to save any key that is not a valid shell identifier
+	ShellKeysStrict = "strict"
+)
+
+// comment marker recording the original key of a renamed variable, so that
+// the file can be loaded back for put.
+const shellKeyComment = "# key: "
+
+var shellIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var shellInvalidChar = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// the ShellSerial reads and writes shell-sourceable files of
+// export KEY='value' lines, using POSIX single-quote escaping.
+type ShellSerial struct {
+	// how keys are turned into variable names, or empty for ShellKeysUnderscore
+	KeyMode string
+}
+
+func (s ShellSerial) Configure(name string, options SerialOptions) Serial {
+	return ShellSerial{KeyMode: options.ShellKeys}
+}
+
+func shellVarName(key string, mode string) (string, error) {
+	if shellIdentifier.MatchString(key) && mode != ShellKeysUpper {
+		return key, nil
+	}
+
+	switch mode {
+	case ShellKeysStrict:
+		return "", errors.New("key is not a valid shell variable name. key " + key)
+	case ShellKeysUnderscore, ShellKeysUpper:
+		name := shellInvalidChar.ReplaceAllString(key, "_")
+		if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		if mode == ShellKeysUpper {
+			name = strings.ToUpper(name)
+		}
+		return name, nil
+	default:
+		return "", errors.New("unknown shell key mode " + mode)
+	}
+}
+
+func quoteShell(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func (s ShellSerial) Save(w io.Writer, dict *map[string]string) error {
+	keyMode := s.KeyMode
+	if len(keyMode) == 0 {
+		keyMode = ShellKeysUnderscore
+	}
+
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	names := make(map[string]string, len(keys))
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name, err := shellVarName(key, keyMode)
+		if err != nil {
+			return err
+		}
+		if other, exists := names[name]; exists {
+			return errors.New("keys " + other + " and " + key + " map to the same shell variable " + name)
+		}
+		names[name] = key
+
+		line := "export " + name + "=" + quoteShell((*dict)[key])
+		if name != key {
+			line += " " + shellKeyComment + key
+		}
+		lines = append(lines, line)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, line := range lines {
+		bw.WriteString(line + "\n")
+	}
+
+	return bw.Flush()
+}
+
+func (s ShellSerial) Load(r io.Reader) (map[string]string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseShell(string(data))
+}
+
+func parseShell(data string) (map[string]string, error) {
+	dict := make(map[string]string)
+	rest := data
+	for len(rest) > 0 {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if len(rest) == 0 {
+			break
+		}
+
+		if rest[0] == '#' {
+			rest = skipLine(rest)
+			continue
+		}
+
+		if strings.HasPrefix(rest, "export ") {
+			rest = strings.TrimLeft(strings.TrimPrefix(rest, "export "), " \t")
+		}
+
+		eq := strings.IndexRune(rest, '=')
+		if eq <= 0 || !shellIdentifier.MatchString(rest[:eq]) {
+			return nil, errors.New("expected NAME=value in shell line: " + strings.SplitN(rest, "\n", 2)[0])
+		}
+
+		name := rest[:eq]
+		value, remainder, err := parseShellWord(rest[eq+1:])
+		if err != nil {
+			return nil, errors.New(err.Error() + ". variable " + name)
+		}
+
+		key := name
+		line := strings.TrimLeft(strings.SplitN(remainder, "\n", 2)[0], " \t")
+		if strings.HasPrefix(line, shellKeyComment) {
+			key = strings.TrimSpace(strings.TrimPrefix(line, shellKeyComment))
+		}
+
+		dict[key] = value
+		rest = skipLine(remainder)
+	}
+
+	return dict, nil
+}
+
+func skipLine(input string) string {
+	if i := strings.IndexRune(input, '\n'); i >= 0 {
+		return input[i+1:]
+	}
+	return ""
+}
+
+// parse a single shell word made of bare, single-quoted and double-quoted
+// segments, returning its value and the input following it.
+func parseShellWord(input string) (string, string, error) {
+	buf := strings.Builder{}
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch c {
+		case ' ', '\t', '\r', '\n', ';':
+			return buf.String(), input[i:], nil
+		case '\'':
+			end := strings.IndexRune(input[i+1:], '\'')
+			if end < 0 {
+				return "", "", errors.New("unterminated single-quoted value")
+			}
+			buf.WriteString(input[i+1 : i+1+end])
+			i += end + 2
+		case '"':
+			i++
+			for ; i < len(input) && input[i] != '"'; i++ {
+				if input[i] == '\\' && i+1 < len(input) && strings.IndexByte("$`\"\\\n", input[i+1]) >= 0 {
+					i++
+				}
+				buf.WriteByte(input[i])
+			}
+			if i >= len(input) {
+				return "", "", errors.New("unterminated double-quoted value")
+			}
+			i++
+		case '\\':
+			if i+1 < len(input) {
+				buf.WriteByte(input[i+1])
+			}
+			i += 2
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+
+	return buf.String(), "", nil
+}
+
+func init() {
+	RegisterSerial(ShellSerial{}, ".sh")
+}
@@ -17,39 +17,32 @@
 package main
 
 import (
+	"errors"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"path"
+	"sort"
 	"strings"
 )
 
 const KeyIdSuffix = "_SecureStringKeyId"
 
-func findAllParametersForPath(ctx *CmdContext, paramPath string) ([]ssm.Parameter, error) {
-	var paramsForPath []ssm.Parameter
-	maxResults := int64(10)
-	recursive := false
-	withDecryption := true
-
-	input := ssm.GetParametersByPathInput{
-		MaxResults:     &maxResults,
-		Path:           &paramPath,
-		WithDecryption: &withDecryption,
-		Recursive:      &recursive}
-
-	request := ctx.Ssms.GetParametersByPathRequest(&input)
-	pager := request.Paginate()
-	for pager.Next() {
-		result := pager.CurrentPage()
-		if len(result.Parameters) > 0 {
-			paramsForPath = append(paramsForPath, result.Parameters...)
-		}
-	}
+// a key with this suffix and a value of "true" marks its buddy key as a
+// comma-separated StringList parameter.
+const StringListSuffix = "_StringList"
 
-	if pager.Err() != nil {
-		return paramsForPath, pager.Err()
-	} else {
-		return paramsForPath, nil
-	}
+// Return true for the sidecar keys that carry parameter metadata, rather than
+// parameter values.
+func isSidecarKey(key string) bool {
+	return strings.HasSuffix(key, KeyIdSuffix) || strings.HasSuffix(key, StringListSuffix)
+}
+
+// Return true if the key is marked as a StringList in the dict.
+func isStringListKey(dict map[string]string, key string) bool {
+	return dict[key+StringListSuffix] == "true"
+}
+
+func findAllParametersForPath(ctx *CmdContext, paramPath string, recursive bool) ([]ssm.Parameter, error) {
+	return ctx.Params.GetParametersByPath(paramPath, recursive)
 }
 
 // If value is all spaces, subtract a space to reconstruct the original value for export.
@@ -79,10 +72,72 @@ func escapeValueBeforePut(value string) string {
 	return value + " "
 }
 
-func getParamsPerPath(ctx *CmdContext, paramPath string, storeDict *map[string]string) error {
-	filterKey, _ := ssm.ParametersFilterKeyName.MarshalValue()
-	filterOption := "Equals"
-	paramsForPath, findErr := findAllParametersForPath(ctx, paramPath)
+// Return true if parameters in sub-paths below the path for filename should be
+// included. Recursion is only enabled by --recursive, even for nested
+// documents, so that clear, sync and delete never reach below the path of a
+// file unless asked to.
+func isRecursiveFor(ctx *CmdContext, filename string) bool {
+	return ctx.Prefs.Recursive
+}
+
+// Return an error if the FileStore for filename holds a nested document with
+// keys below its top level, which would be put to parameter sub-paths that can
+// only be read back with --recursive.
+func requireRecursiveFor(ctx *CmdContext, filename string) error {
+	store := ctx.Stores[filename]
+	if isRecursiveFor(ctx, filename) || !store.IsNested() {
+		return nil
+	}
+
+	for key := range store.Dict {
+		if strings.Contains(key, NestedKeySeparator) {
+			return errors.New("nested keys are put to parameter sub-paths, which requires --recursive. key " + key)
+		}
+	}
+	return nil
+}
+
+// Return the separator that joins the sub-path levels of a parameter name in
+// the keys of the FileStore for filename. Nested documents always use the
+// NestedKeySeparator, and so does every file without --recursive, so that a
+// key like db.url is put to a parameter named db.url.
+func keySeparatorFor(ctx *CmdContext, filename string) string {
+	if !isRecursiveFor(ctx, filename) || ctx.Stores[filename].IsNested() || len(ctx.Prefs.KeySeparator) == 0 {
+		return NestedKeySeparator
+	}
+	return ctx.Prefs.KeySeparator
+}
+
+// Return true if the _SecureStringKeyId sidecar of each SecureString should be
+// retrieved for filename, either because --get-key-id was specified or because
+// the file format needs it to tell secrets apart.
+func wantsKeyIdFor(ctx *CmdContext, filename string) bool {
+	return ctx.Prefs.GetKeyId || ctx.Stores[filename].NeedsKeyId()
+}
+
+// Build the SSM parameter name for a FileStore key, mapping the key separator
+// back onto parameter sub-paths.
+func buildParameterPathForKey(ctx *CmdContext, prefix string, filename string, key string) string {
+	if sep := keySeparatorFor(ctx, filename); sep != NestedKeySeparator {
+		key = strings.Replace(key, sep, NestedKeySeparator, -1)
+	}
+	return buildParameterPath(prefix, filename, key)
+}
+
+// Merge the parameters below paramPath into storeDict, keyed by the remainder
+// of each parameter name. Parameters in sub-paths are only merged when
+// isRecursiveFor the filename. When selector is not empty, the version of each
+// parameter it picks is merged instead of the latest. When paramTypes is not
+// nil, the type of each merged parameter is recorded by key.
+func getParamsPerPath(ctx *CmdContext, filename string, paramPath string, selector string,
+	storeDict *map[string]string, paramTypes map[string]ssm.ParameterType) error {
+	keySep := keySeparatorFor(ctx, filename)
+	paramsForPath, findErr := findAllParametersForPath(ctx, paramPath, isRecursiveFor(ctx, filename))
+	if findErr != nil {
+		return findErr
+	}
+
+	paramsForPath, findErr = selectParams(ctx, paramsForPath, selector)
 	if findErr != nil {
 		return findErr
 	}
@@ -90,8 +145,7 @@ func getParamsPerPath(ctx *CmdContext, paramPath string, storeDict *map[string]s
 	for _, param := range paramsForPath {
 		name := *param.Name
 
-		if param.Type == ssm.ParameterTypeStringList ||
-			(ctx.Prefs.NoGetSecureString && param.Type == ssm.ParameterTypeSecureString) {
+		if ctx.Prefs.NoGetSecureString && param.Type == ssm.ParameterTypeSecureString {
 			continue
 		}
 
@@ -100,26 +154,29 @@ func getParamsPerPath(ctx *CmdContext, paramPath string, storeDict *map[string]s
 		}
 
 		storeKey := strings.TrimPrefix(name, paramPath+"/")
+		if keySep != NestedKeySeparator {
+			storeKey = strings.Replace(storeKey, NestedKeySeparator, keySep, -1)
+		}
 		(*storeDict)[storeKey] = unescapeValueAfterGet(*param.Value)
+		if paramTypes != nil {
+			paramTypes[storeKey] = param.Type
+		}
+
+		if param.Type == ssm.ParameterTypeStringList {
+			(*storeDict)[storeKey+StringListSuffix] = "true"
+		} else {
+			delete(*storeDict, storeKey+StringListSuffix)
+		}
 
-		if param.Type == ssm.ParameterTypeSecureString && ctx.Prefs.GetKeyId {
+		if param.Type == ssm.ParameterTypeSecureString && wantsKeyIdFor(ctx, filename) {
 			sidecarStoreKey := storeKey + KeyIdSuffix
-			input := ssm.DescribeParametersInput{}
-			input.ParameterFilters = append(input.ParameterFilters,
-				ssm.ParameterStringFilter{
-					Key:    &filterKey,
-					Option: &filterOption,
-					Values: []string{name}})
-
-			result, err := ctx.Ssms.DescribeParametersRequest(&input).Send()
+			meta, err := ctx.Params.DescribeParameter(name)
 			if err != nil {
 				return err
 			}
 
-			if len(result.Parameters) > 0 {
-				if result.Parameters[0].KeyId != nil {
-					(*storeDict)[sidecarStoreKey] = ctx.KmsMap.aliasFor(*result.Parameters[0].KeyId)
-				}
+			if meta != nil && meta.KeyId != nil {
+				(*storeDict)[sidecarStoreKey] = ctx.KmsMap.aliasFor(*meta.KeyId)
 			}
 		}
 	}
@@ -128,7 +185,8 @@ func getParamsPerPath(ctx *CmdContext, paramPath string, storeDict *map[string]s
 
 // Build an SSM parameter path or name.
 // prefix:   hierarchy levels 0-(N-2)
-// filename: hierarchy level N-1 (.properties, .json, or .yaml extensions will be stripped)
+// filename: hierarchy level N-1 (.properties, .json, or .yaml extensions will be stripped, as will
+//           registered compound extensions like .k8s.yaml)
 // key:      optional, hierarchy level N
 // TODO make platform independent (i.e., this won't work on windows)
 func buildParameterPath(prefix string, filename string, key string) string {
@@ -147,8 +205,7 @@ func buildParameterPath(prefix string, filename string, key string) string {
 	realfn := path.Base(base)
 
 	if len(realfn) > 0 && strings.ContainsRune(realfn, '.') {
-		fnRunes := []rune(realfn)
-		realfn = string(fnRunes[0:strings.LastIndex(realfn, ".")])
+		realfn = strings.TrimSuffix(realfn, serialExt(realfn))
 	}
 
 	if len(key) > 0 {
@@ -158,57 +215,154 @@ func buildParameterPath(prefix string, filename string, key string) string {
 	}
 }
 
-func getParamsPerFile(ctx *CmdContext, filename string) error {
-	prefixes := ctx.Prefs.Prefixes
-	store := ctx.Stores[filename]
-	for _, prefix := range prefixes {
+// Merge the parameters for filename from each -s prefix into storeDict, in
+// the order the prefixes were declared.
+func mergeParamsPerFile(ctx *CmdContext, filename string, storeDict *map[string]string,
+	paramTypes map[string]ssm.ParameterType) error {
+	for i, prefix := range ctx.Prefs.Prefixes {
 		paramPath := buildParameterPath(prefix, filename, "")
-		if err := getParamsPerPath(ctx, paramPath, &store.Dict); err != nil {
+		if err := getParamsPerPath(ctx, filename, paramPath, prefixSelectorAt(ctx, i), storeDict, paramTypes); err != nil {
 			return err
 		}
 	}
 
-	if len(store.Dict) > 0 {
-		return store.Save()
+	return nil
+}
+
+// strategies for merging the existing local file with the parameters retrieved
+// on get.
+const (
+	// write only the parameters retrieved from SSM
+	MergeReplace = "replace"
+
+	// overlay the parameters retrieved from SSM onto the existing local file
+	MergeOverlay = "overlay"
+
+	// add the parameters retrieved from SSM, keeping the values of the existing
+	// local file
+	MergePreserveLocal = "preserve-local"
+)
+
+// Merge the parameters for filename into its FileStore according to the
+// --merge strategy, and save it. Returns the stale keys of the existing local
+// file, which were not retrieved from SSM, in sorted order.
+func getParamsPerFile(ctx *CmdContext, filename string) ([]string, error) {
+	store := ctx.Stores[filename]
+	local := store.Dict
+
+	merged := local
+	if ctx.Prefs.Merge == MergeReplace || ctx.Prefs.Merge == MergePreserveLocal {
+		merged = make(map[string]string, len(local))
+	}
+
+	paramTypes := make(map[string]ssm.ParameterType, 0)
+	if err := mergeParamsPerFile(ctx, filename, &merged, paramTypes); err != nil {
+		return nil, err
 	}
 
-	return nil
+	stale := make([]string, 0)
+	for key := range local {
+		if _, retrieved := paramTypes[key]; !retrieved && !isSidecarKey(key) {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+
+	if ctx.Prefs.Merge == MergePreserveLocal {
+		for key, value := range local {
+			merged[key] = value
+		}
+	}
+	store.Dict = merged
+
+	store.Secure = hasSecureStringType(paramTypes)
+
+	// save when the merge removed the last stale keys, too.
+	if len(store.Dict) > 0 || len(local) > 0 {
+		return stale, store.Save()
+	}
+
+	return stale, nil
+}
+
+// Return true if any of the recorded paramTypes is SecureString.
+func hasSecureStringType(paramTypes map[string]ssm.ParameterType) bool {
+	for _, paramType := range paramTypes {
+		if paramType == ssm.ParameterTypeSecureString {
+			return true
+		}
+	}
+	return false
 }
 
 func clearParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
 	paramPath := buildParameterPath(prefix, filename, "")
-	params, findErr := findAllParametersForPath(ctx, paramPath)
+	params, findErr := findAllParametersForPath(ctx, paramPath, isRecursiveFor(ctx, filename))
 	if findErr != nil {
 		return findErr
 	}
-	count := len(params)
-	names := make([]string, count)
-	i := 0
-	for _, param := range params {
+	names := make([]string, len(params))
+	for i, param := range params {
 		names[i] = *param.Name
-		i++
 	}
 
-	batchSize := 10
-	batches := (count / batchSize) + 1
-	for b := 0; b < batches; b++ {
-		input := ssm.DeleteParametersInput{}
-		if b+1 < batches {
-			input.Names = append(input.Names, names[batchSize*b:batchSize*(b+1)]...)
-		} else {
-			input.Names = append(input.Names, names[batchSize*b:]...)
-		}
-		if len(input.Names) > 0 {
-			if _, err := ctx.Ssms.DeleteParametersRequest(&input).Send(); err != nil {
-				return err
-			}
+	return ctx.Params.DeleteParameters(names)
+}
+
+// Build the PutParameterInput for a single key of a FileStore, using its
+// _SecureStringKeyId and _StringList sidecars to choose the type and KMS key.
+// Returns nil for sidecar keys and for keys that should not be put.
+func buildPutParameterInput(ctx *CmdContext, store *FileStore, filename string, prefix string, key string) *ssm.PutParameterInput {
+	if isSidecarKey(key) {
+		return nil
+	}
+	sidecarKeyId := key + KeyIdSuffix
+	name := buildParameterPathForKey(ctx, prefix, filename, key)
+
+	keyId, isSecure := store.Dict[sidecarKeyId]
+	if isSecure && ctx.Prefs.NoPutSecureString {
+		return nil
+	}
+
+	// StringList parameters can not be encrypted, so they keep their type
+	// even when a key is specified to encrypt all parameters.
+	isStringList := isStringListKey(store.Dict, key)
+	if len(ctx.Prefs.KeyIdPutAll) > 0 && !isStringList {
+		isSecure = true
+		keyId = ctx.Prefs.KeyIdPutAll
+	}
+
+	// an empty sidecar selects the default KMS key for SecureStrings
+	if len(keyId) > 0 {
+		keyId = ctx.KmsMap.deref(keyId)
+	}
+
+	escaped := escapeValueBeforePut(store.Dict[key])
+	input := ssm.PutParameterInput{}
+
+	input.Name = &name
+	input.Value = &escaped
+	input.Overwrite = &ctx.Prefs.OverwritePut
+
+	if isStringList {
+		input.Type = ssm.ParameterTypeStringList
+	} else if isSecure {
+		if len(keyId) > 0 {
+			input.KeyId = &keyId
 		}
+		input.Type = ssm.ParameterTypeSecureString
+	} else {
+		input.Type = ssm.ParameterTypeString
 	}
 
-	return nil
+	return &input
 }
 
 func putParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
+	if err := requireRecursiveFor(ctx, filename); err != nil {
+		return err
+	}
+
 	if ctx.Prefs.ClearOnPut {
 		if err := clearParamsPerFile(ctx, filename, prefix); err != nil {
 			return err
@@ -216,57 +370,132 @@ func putParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
 	}
 
 	store := ctx.Stores[filename]
-	for key, value := range store.Dict {
-		if strings.HasSuffix(key, KeyIdSuffix) {
+	for key := range store.Dict {
+		input := buildPutParameterInput(ctx, store, filename, prefix, key)
+		if input == nil {
 			continue
 		}
-		sidecarKeyId := key + KeyIdSuffix
-		name := buildParameterPath(prefix, filename, key)
 
-		keyId, isSecure := store.Dict[sidecarKeyId]
-		if isSecure && ctx.Prefs.NoPutSecureString {
-			continue
+		if err := ctx.Params.PutParameter(input); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// Make the parameters at the path for filename under prefix match the FileStore,
+// putting only new or changed keys and deleting only parameters whose keys are
+// missing from the file. Unchanged parameters keep their current version.
+func syncParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
+	if err := requireRecursiveFor(ctx, filename); err != nil {
+		return err
+	}
+
+	paramPath := buildParameterPath(prefix, filename, "")
+	params, findErr := findAllParametersForPath(ctx, paramPath, isRecursiveFor(ctx, filename))
+	if findErr != nil {
+		return findErr
+	}
 
-		if len(ctx.Prefs.KeyIdPutAll) > 0 {
-			isSecure = true
-			keyId = ctx.Prefs.KeyIdPutAll
+	remote := make(map[string]ssm.Parameter, len(params))
+	for _, param := range params {
+		remote[*param.Name] = param
+	}
+
+	store := ctx.Stores[filename]
+	keep := make(map[string]bool, len(store.Dict))
+	var toPut []*ssm.PutParameterInput
+	for key := range store.Dict {
+		if isSidecarKey(key) {
+			continue
 		}
 
-		keyId = ctx.KmsMap.deref(keyId)
+		name := buildParameterPathForKey(ctx, prefix, filename, key)
+		keep[name] = true
 
-		escaped := escapeValueBeforePut(value)
-		input := ssm.PutParameterInput{}
+		input := buildPutParameterInput(ctx, store, filename, prefix, key)
+		if input == nil {
+			continue
+		}
 
-		input.Name = &name
-		input.Value = &escaped
-		input.Overwrite = &ctx.Prefs.OverwritePut
+		if param, exists := remote[name]; exists {
+			// a SecureString losing its sidecar key would be stored in plain text
+			if param.Type == ssm.ParameterTypeSecureString && input.Type != ssm.ParameterTypeSecureString &&
+				!ctx.Prefs.Force {
+				return errors.New("refusing to replace SecureString parameter " + name + " with a " +
+					string(input.Type) + ". use --force to replace it")
+			}
 
-		if isSecure {
-			input.KeyId = &keyId
-			input.Type = ssm.ParameterTypeSecureString
-		} else {
-			input.Type = ssm.ParameterTypeString
+			changed, err := isParameterChanged(ctx, param, input)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
 		}
 
-		_, err := ctx.Ssms.PutParameterRequest(&input).Send()
-		if err != nil {
+		toPut = append(toPut, input)
+	}
+
+	for _, input := range toPut {
+		overwrite := true
+		input.Overwrite = &overwrite
+		if err := ctx.Params.PutParameter(input); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	var toDelete []string
+	for _, param := range params {
+		if keep[*param.Name] {
+			continue
+		}
+		if param.Type == ssm.ParameterTypeSecureString && ctx.Prefs.NoPutSecureString {
+			continue
+		}
+		toDelete = append(toDelete, *param.Name)
+	}
+
+	return ctx.Params.DeleteParameters(toDelete)
+}
+
+// Compare an existing parameter with the input that would replace it. The KMS
+// key is only described when both are SecureStrings with equal values.
+func isParameterChanged(ctx *CmdContext, param ssm.Parameter, input *ssm.PutParameterInput) (bool, error) {
+	if param.Type != input.Type || *param.Value != *input.Value {
+		return true, nil
+	}
+
+	if input.Type != ssm.ParameterTypeSecureString {
+		return false, nil
+	}
+
+	meta, err := ctx.Params.DescribeParameter(*param.Name)
+	if err != nil {
+		return false, err
+	}
+
+	// SSM encrypts with the default key when no KeyId is given.
+	keyId := DefaultSecureStringKeyId
+	if input.KeyId != nil {
+		keyId = *input.KeyId
+	}
+
+	return meta == nil || meta.KeyId == nil ||
+		ctx.KmsMap.resolve(*meta.KeyId) != ctx.KmsMap.resolve(keyId), nil
 }
 
 func deleteParamsPerFile(ctx *CmdContext, filename string, prefix string) error {
 	var names []string
 	store := ctx.Stores[filename]
 	for key := range store.Dict {
-		names = append(names, buildParameterPath(prefix, filename, key))
+		names = append(names, buildParameterPathForKey(ctx, prefix, filename, key))
 	}
 
 	paramPath := buildParameterPath(prefix, filename, "")
-	allParams, findErr := findAllParametersForPath(ctx, paramPath)
+	allParams, findErr := findAllParametersForPath(ctx, paramPath, isRecursiveFor(ctx, filename))
 	if findErr != nil {
 		return findErr
 	}
@@ -286,22 +515,5 @@ func deleteParamsPerFile(ctx *CmdContext, filename string, prefix string) error
 		}
 	}
 
-	count := len(toDelete)
-	batchSize := 10
-	batches := (count / batchSize) + 1
-	for b := 0; b < batches; b++ {
-		input := ssm.DeleteParametersInput{}
-		if b+1 < batches {
-			input.Names = append(input.Names, names[batchSize*b:batchSize*(b+1)]...)
-		} else {
-			input.Names = append(input.Names, names[batchSize*b:]...)
-		}
-		if len(input.Names) > 0 {
-			if _, err := ctx.Ssms.DeleteParametersRequest(&input).Send(); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+	return ctx.Params.DeleteParameters(toDelete)
 }
@@ -16,7 +16,13 @@
 
 package main
 
-import "testing"
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
 
 func assertBuildParameterPath(t *testing.T, prefix string, filename string, key string, expected string) {
 	if result := buildParameterPath(prefix, filename, key);
@@ -42,4 +48,420 @@ func TestBuildParameterPath(t *testing.T) {
 		"/alpha/beta/file/myprop")
 	assertBuildParameterPath(t, "/alpha/beta", "../two/one/./file.properties", "myprop",
 		"/alpha/two/one/file/myprop")
+	assertBuildParameterPath(t, "/alpha/beta", "one/app.k8s.yaml", "myprop",
+		"/alpha/beta/one/app/myprop")
+}
+
+// build a CmdContext backed by a MemParameterStore, with a temporary conf dir
+// holding an empty FileStore for each filename.
+func newTestContext(t *testing.T, prefixes []string, filenames ...string) *CmdContext {
+	confDir, err := ioutil.TempDir("", "ssmple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := make(map[string]*FileStore, len(filenames))
+	for _, fn := range filenames {
+		fs := NewFileStore(confDir, fn)
+		stores[fn] = &fs
+	}
+
+	return &CmdContext{
+		Prefs: ParsedArgs{
+			ConfDir:   confDir,
+			Filenames: filenames,
+			Prefixes:  prefixes},
+		Stores: stores,
+		Params: NewMemParameterStore(),
+		KmsMap: KmsMap{
+			aliasesToKeys: make(map[string]string, 0),
+			keysToAliases: make(map[string]string, 0)}}
+}
+
+func putTestParam(t *testing.T, ctx *CmdContext, name string, value string, paramType ssm.ParameterType) {
+	input := ssm.PutParameterInput{Name: &name, Value: &value, Type: paramType}
+	if err := ctx.Params.PutParameter(&input); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertDict(t *testing.T, dict map[string]string, expected map[string]string) {
+	if !reflect.DeepEqual(dict, expected) {
+		t.Errorf("dict does not match. expected: %v, actual: %v\n", expected, dict)
+	}
+}
+
+func TestGetParamsPerFileLayering(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf", "/ep/conf/prod"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/port", "8080", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/host", "prod.example.com", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/password", "secret", ssm.ParameterTypeSecureString)
+	putTestParam(t, ctx, "/ep/conf/prod/ecs/deeper/ignored", "ignored", ssm.ParameterTypeString)
+
+	if _, err := getParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertDict(t, ctx.Stores["ecs.properties"].Dict, map[string]string{
+		"host":     "prod.example.com",
+		"port":     "8080",
+		"password": "secret"})
+
+	reloaded := NewFileStore(ctx.Prefs.ConfDir, "ecs.properties")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, reloaded.Dict, ctx.Stores["ecs.properties"].Dict)
+}
+
+func TestGetParamsPerFileMerge(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/port", "8080", ssm.ParameterTypeString)
+
+	local := map[string]string{"host": "local.example.com", "stale": "leftover"}
+	expectations := map[string]map[string]string{
+		MergeOverlay:       {"host": "localhost", "port": "8080", "stale": "leftover"},
+		MergeReplace:       {"host": "localhost", "port": "8080"},
+		MergePreserveLocal: {"host": "local.example.com", "port": "8080", "stale": "leftover"}}
+
+	for merge, expected := range expectations {
+		ctx.Prefs.Merge = merge
+		store := ctx.Stores["ecs.properties"]
+		store.Dict = make(map[string]string)
+		for key, value := range local {
+			store.Dict[key] = value
+		}
+
+		stale, err := getParamsPerFile(ctx, "ecs.properties")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stale, []string{"stale"}) {
+			t.Errorf("unexpected stale keys for %s merge: %v\n", merge, stale)
+		}
+		assertDict(t, store.Dict, expected)
+	}
+}
+
+func TestPutAndDeleteParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["host"] = "localhost"
+	store.Dict["empty"] = ""
+	store.Dict["password"] = "secret"
+	store.Dict["password"+KeyIdSuffix] = "alias/mykey"
+
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/password")
+	if meta == nil || meta.Type != ssm.ParameterTypeSecureString || *meta.KeyId != "alias/mykey" {
+		t.Errorf("password should be a SecureString encrypted with alias/mykey. actual: %v\n", meta)
+	}
+
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err == nil {
+		t.Error("put without overwrite should fail for existing parameters")
+	}
+
+	dict := make(map[string]string)
+	if err := getParamsPerPath(ctx, "ecs.properties", "/ep/conf/ecs", "", &dict, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, dict, map[string]string{
+		"host":     "localhost",
+		"empty":    "",
+		"password": "secret"})
+
+	putTestParam(t, ctx, "/ep/conf/ecs/other", "other", ssm.ParameterTypeString)
+	delete(store.Dict, "password"+KeyIdSuffix)
+	if err := deleteParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, _ := findAllParametersForPath(ctx, "/ep/conf/ecs", false)
+	if len(remaining) != 1 || *remaining[0].Name != "/ep/conf/ecs/other" {
+		t.Errorf("delete should only remove parameters named in the file. remaining: %v\n", remaining)
+	}
+
+	if err := clearParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _ := findAllParametersForPath(ctx, "/ep/conf/ecs", false); len(remaining) != 0 {
+		t.Errorf("clear should remove all parameters. remaining: %v\n", remaining)
+	}
+}
+
+func TestSyncParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/same", "same", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/changed", "old", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/secured", "secret", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/removed", "gone", ssm.ParameterTypeString)
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["same"] = "same"
+	store.Dict["changed"] = "new"
+	store.Dict["secured"] = "secret"
+	store.Dict["secured"+KeyIdSuffix] = "alias/mykey"
+	store.Dict["added"] = "added"
+
+	if err := syncParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	dict := make(map[string]string)
+	if err := getParamsPerPath(ctx, "ecs.properties", "/ep/conf/ecs", "", &dict, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, dict, map[string]string{
+		"same":    "same",
+		"changed": "new",
+		"secured": "secret",
+		"added":   "added"})
+
+	versions := map[string]int64{"same": 1, "changed": 2, "secured": 2, "added": 1}
+	for key, version := range versions {
+		meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/" + key)
+		if *meta.Version != version {
+			t.Errorf("expected version %d for key %s. actual: %d\n", version, key, *meta.Version)
+		}
+	}
+
+	if err := syncParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/secured"); *meta.Version != 2 {
+		t.Errorf("a second sync should not change anything. actual version: %d\n", *meta.Version)
+	}
+}
+
+func TestSyncParamsPerFileNoPutSecureString(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+	ctx.Prefs.NoPutSecureString = true
+
+	putTestParam(t, ctx, "/ep/conf/ecs/password", "secret", ssm.ParameterTypeSecureString)
+	putTestParam(t, ctx, "/ep/conf/ecs/removed", "gone", ssm.ParameterTypeString)
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["host"] = "localhost"
+
+	if err := syncParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/password"); meta == nil {
+		t.Error("expected --no-put-secure-string to keep SecureString parameters missing from the file")
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/removed"); meta != nil {
+		t.Error("expected String parameters missing from the file to be deleted")
+	}
+}
+
+func TestSyncParamsPerFileSecureStringToString(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/password", "secret", ssm.ParameterTypeSecureString)
+
+	store := ctx.Stores["ecs.properties"]
+	store.Dict["password"] = "secret"
+	store.Dict["host"] = "localhost"
+
+	if err := syncParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err == nil {
+		t.Fatal("expected sync to refuse to replace a SecureString with a String")
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/host"); meta != nil {
+		t.Error("expected a refused sync to put nothing")
+	}
+
+	ctx.Prefs.Force = true
+	if err := syncParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/password"); meta == nil || meta.Type != ssm.ParameterTypeString {
+		t.Errorf("expected --force to replace the SecureString with a String. actual: %v\n", meta)
+	}
+}
+
+func TestSyncParamsPerFileEmptyKeyId(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "app.k8s.yaml")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	// k8s Secret entries without an annotation load with an empty sidecar,
+	// which selects the default KMS key.
+	store := ctx.Stores["app.k8s.yaml"]
+	store.Dict["password"] = "secret"
+	store.Dict["password"+KeyIdSuffix] = ""
+
+	for i := 0; i < 2; i++ {
+		if err := syncParamsPerFile(ctx, "app.k8s.yaml", "/ep/conf"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta, _ := ctx.Params.DescribeParameter("/ep/conf/app/password")
+	if meta == nil || meta.Type != ssm.ParameterTypeSecureString || *meta.KeyId != DefaultSecureStringKeyId {
+		t.Fatalf("expected a SecureString with the default key. actual: %v\n", meta)
+	}
+	if *meta.Version != 1 {
+		t.Errorf("a second sync should not change anything. actual version: %d\n", *meta.Version)
+	}
+}
+
+func TestNestedParamsRoundTrip(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "application.yml")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	store := ctx.Stores["application.yml"]
+	store.Dict["server/port"] = "8080"
+	store.Dict["spring/datasource/url"] = "jdbc:h2:mem"
+	store.Dict["name"] = "app"
+
+	if err := putParamsPerFile(ctx, "application.yml", "/ep/conf"); err == nil {
+		t.Error("expected put of nested keys to require --recursive")
+	}
+
+	ctx.Prefs.Recursive = true
+	if err := putParamsPerFile(ctx, "application.yml", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/application/spring/datasource/url"); meta == nil {
+		t.Error("expected nested keys to be put at parameter sub-paths")
+	}
+
+	expected := store.Dict
+	store.Dict = make(map[string]string)
+	if _, err := getParamsPerFile(ctx, "application.yml"); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, store.Dict, expected)
+}
+
+func TestClearNestedFileIsNotRecursive(t *testing.T) {
+	ctx := newTestContext(t, []string{"/p"}, "app.yaml")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/p/app/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/p/app/a/b", "owned elsewhere", ssm.ParameterTypeString)
+
+	if err := clearParamsPerFile(ctx, "app.yaml", "/p"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/p/app/host"); meta != nil {
+		t.Error("expected clear to delete the parameters directly below the file path")
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/p/app/a/b"); meta == nil {
+		t.Error("expected clear without --recursive to leave parameters in sub-paths alone")
+	}
+}
+
+func TestGetNestedValueConflict(t *testing.T) {
+	ctx := newTestContext(t, []string{"/p"}, "app.yaml")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+	ctx.Prefs.Recursive = true
+
+	putTestParam(t, ctx, "/p/app/x", "value", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/p/app/x/y", "nested", ssm.ParameterTypeString)
+
+	if _, err := getParamsPerFile(ctx, "app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewFileStore(ctx.Prefs.ConfDir, "app.yaml")
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, reloaded.Dict, map[string]string{"x": "value"})
+}
+
+func TestRecursiveParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/host", "localhost", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/db/url", "jdbc:h2:mem", ssm.ParameterTypeString)
+	putTestParam(t, ctx, "/ep/conf/ecs/db/pool/size", "10", ssm.ParameterTypeString)
+
+	store := ctx.Stores["ecs.properties"]
+	if _, err := getParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, store.Dict, map[string]string{"host": "localhost"})
+
+	ctx.Prefs.Recursive = true
+	ctx.Prefs.KeySeparator = "."
+	store.Dict = make(map[string]string)
+	if _, err := getParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"host":         "localhost",
+		"db.url":       "jdbc:h2:mem",
+		"db.pool.size": "10"}
+	assertDict(t, store.Dict, expected)
+
+	if err := clearParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _ := findAllParametersForPath(ctx, "/ep/conf/ecs", true); len(remaining) != 0 {
+		t.Errorf("recursive clear should remove all parameters. remaining: %v\n", remaining)
+	}
+
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/db/pool/size"); meta == nil {
+		t.Error("expected dotted keys to be put at parameter sub-paths")
+	}
+
+	ctx.Prefs.Recursive = false
+	ctx.Prefs.OverwritePut = true
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/db.pool.size"); meta == nil {
+		t.Error("expected --key-separator to be ignored without --recursive")
+	}
+}
+
+func TestStringListParamsPerFile(t *testing.T) {
+	ctx := newTestContext(t, []string{"/ep/conf"}, "ecs.properties")
+	defer os.RemoveAll(ctx.Prefs.ConfDir)
+
+	putTestParam(t, ctx, "/ep/conf/ecs/hosts", "alpha,beta", ssm.ParameterTypeStringList)
+
+	store := ctx.Stores["ecs.properties"]
+	if _, err := getParamsPerFile(ctx, "ecs.properties"); err != nil {
+		t.Fatal(err)
+	}
+	assertDict(t, store.Dict, map[string]string{
+		"hosts":                    "alpha,beta",
+		"hosts" + StringListSuffix: "true"})
+
+	ctx.Prefs.OverwritePut = true
+	ctx.Prefs.KeyIdPutAll = "alias/mykey"
+	store.Dict["hosts"] = "alpha,beta,gamma"
+	if err := putParamsPerFile(ctx, "ecs.properties", "/ep/conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/hosts")
+	if meta.Type != ssm.ParameterTypeStringList || *meta.Version != 2 {
+		t.Errorf("expected hosts to be put as a StringList. actual: %v\n", meta)
+	}
+	if meta, _ := ctx.Params.DescribeParameter("/ep/conf/ecs/hosts" + StringListSuffix); meta != nil {
+		t.Error("the StringList marker should not be put as a parameter")
+	}
 }
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/BurntSushi/toml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the TomlSerial reads and writes TOML documents. Tables are flattened onto
+// parameter sub-paths like nested JSON and YAML objects. Because parameter
+// values are strings, values are saved as strings, except for values that were
+// integers, floats, booleans or datetimes in the existing file, which are
+// inferred from their string form again.
+type TomlSerial struct {
+	// true to also infer the types of values that are not in the existing
+	// file. values that were strings in the existing file, like "8080", are
+	// kept as strings.
+	InferTypes bool
+}
+
+var tomlInteger = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)$`)
+var tomlFloat = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func (s TomlSerial) Load(r io.Reader) (map[string]string, error) {
+	var m map[string]interface{}
+	if _, err := toml.DecodeReader(r, &m); err != nil {
+		return nil, err
+	}
+
+	return flattenDoc(formatTomlFloats(m))
+}
+
+// Replace float values with strings that keep their decimal point, so that they
+// are not inferred as integers when saved.
+func formatTomlFloats(doc map[string]interface{}) map[string]interface{} {
+	for k, v := range doc {
+		doc[k] = formatTomlFloat(v)
+	}
+	return doc
+}
+
+func formatTomlFloat(v interface{}) interface{} {
+	switch v.(type) {
+	case float64:
+		f := strconv.FormatFloat(v.(float64), 'g', -1, 64)
+		if !strings.ContainsAny(f, ".eEnN") {
+			f += ".0"
+		}
+		return f
+	case map[string]interface{}:
+		return formatTomlFloats(v.(map[string]interface{}))
+	case []interface{}:
+		items := v.([]interface{})
+		for i, item := range items {
+			items[i] = formatTomlFloat(item)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+func (s TomlSerial) Save(w io.Writer, dict *map[string]string) error {
+	return s.SaveOver(w, nil, dict)
+}
+
+func (s TomlSerial) SaveOver(w io.Writer, previous io.Reader, dict *map[string]string) error {
+	doc := nestDict(*dict)
+
+	quoted := make(map[string]bool)
+	if previous != nil {
+		var existing map[string]interface{}
+		if _, err := toml.DecodeReader(previous, &existing); err == nil {
+			findTomlStrings(existing, "", quoted)
+		}
+	}
+
+	enc := toml.NewEncoder(w)
+	return enc.Encode(inferTomlTypes(doc, "", quoted, s.InferTypes))
+}
+
+func (s TomlSerial) Configure(name string, options SerialOptions) Serial {
+	return TomlSerial{InferTypes: options.TomlInferTypes}
+}
+
+// Record whether the values of a nested document are strings or string
+// arrays in quoted, keyed by their keys joined by NestedKeySeparator below
+// prefix.
+func findTomlStrings(doc map[string]interface{}, prefix string, quoted map[string]bool) {
+	for k, v := range doc {
+		switch v.(type) {
+		case string:
+			quoted[prefix+k] = true
+		case map[string]interface{}:
+			findTomlStrings(v.(map[string]interface{}), prefix+k+NestedKeySeparator, quoted)
+		case []interface{}:
+			quoted[prefix+k] = false
+			if items := v.([]interface{}); len(items) > 0 {
+				if _, isString := items[0].(string); isString {
+					quoted[prefix+k] = true
+				}
+			}
+		default:
+			quoted[prefix+k] = false
+		}
+	}
+}
+
+// tables are flattened into keys joined by NestedKeySeparator.
+func (s TomlSerial) IsNested() bool {
+	return true
+}
+
+// Replace the string values of a nested document with typed TOML values where
+// their string form allows. Values whose key below prefix is in quoted are
+// only typed when they were not strings, and the other values only when infer
+// is true. Arrays are only typed when every item infers the same type, because
+// TOML arrays must be homogeneous.
+func inferTomlTypes(doc map[string]interface{}, prefix string, quoted map[string]bool, infer bool) map[string]interface{} {
+	typed := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if child, isMap := v.(map[string]interface{}); isMap {
+			typed[k] = inferTomlTypes(child, prefix+k+NestedKeySeparator, quoted, infer)
+			continue
+		}
+
+		if isString, exists := quoted[prefix+k]; isString || (!exists && !infer) {
+			typed[k] = v
+			continue
+		}
+
+		switch v.(type) {
+		case []string:
+			typed[k] = inferTomlArray(v.([]string))
+		case string:
+			typed[k] = inferTomlScalar(v.(string))
+		default:
+			typed[k] = v
+		}
+	}
+	return typed
+}
+
+func inferTomlArray(items []string) interface{} {
+	typed := make([]interface{}, len(items))
+	for i, item := range items {
+		typed[i] = inferTomlScalar(item)
+		if i > 0 && tomlTypeName(typed[i]) != tomlTypeName(typed[0]) {
+			return items
+		}
+	}
+	return typed
+}
+
+func tomlTypeName(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "datetime"
+	default:
+		return "string"
+	}
+}
+
+func inferTomlScalar(value string) interface{} {
+	if tomlInteger.MatchString(value) {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	}
+	if tomlFloat.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	return value
+}
+
+func init() {
+	RegisterSerial(TomlSerial{}, ".toml")
+}
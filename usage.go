@@ -35,7 +35,12 @@ GLOBAL OPTIONS
   -h | --help                           : print this help message
   -p | --profile                        : set AWS profile
   -r | --region                         : set AWS region
-       --use-ec2-role                   : allow attempt to resolve EC2 instance role credentials from host endpoint`, argv0)
+       --use-ec2-role                   : allow attempt to resolve EC2 instance role credentials from host endpoint
+       --dry-run                        : print each PutParameter and DeleteParameters request made by put, delete and clear,
+                                          including type and KMS key, without sending it.
+       --backend                        : set the parameter store backend. Defaults to "ssm". Specify file:///some/dir to emulate
+                                          the SSM parameter hierarchy in a local directory, without AWS credentials. SecureString
+                                          values are stored unencrypted by the file backend.`, argv0)
 
 	fmt.Println(globalHelp)
 	fmt.Println(help(operation))
@@ -47,10 +52,30 @@ func help(operation string) string {
 		return helpGet()
 	case "put":
 		return helpPut()
+	case "sync":
+		return helpSync()
 	case "delete":
 		return helpDelete()
 	case "clear":
 		return helpClear()
+	case "diff":
+		return helpDiff()
+	case "exec":
+		return helpExec()
+	case "render":
+		return helpRender()
+	case "export":
+		return helpExport()
+	case "import":
+		return helpImport()
+	case "copy", "promote":
+		return helpCopy()
+	case "history":
+		return helpHistory()
+	case "rollback":
+		return helpRollback()
+	case "label":
+		return helpLabel()
 	default:
 		return helpOperations()
 	}
@@ -66,19 +91,52 @@ OPERATION
 
     USAGE
 
-      %[1]s get [ --no-get-secure-string ] [ --get-key-id ] -s <prefix> [ [ -s <prefix> ] ... ] [ -C <confDir> ] 
-            -f filename [ [ -f filename ] ... ]
+      %[1]s get [ --no-get-secure-string ] [ --get-key-id ] [ -R [ --key-separator <sep> ] ] [ --lock <file> ]
+            -s <prefix>[:<selector>] [ [ -s <prefix>[:<selector>] ] ... ] [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
 
     OPTIONS
 
       -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
-                                          they are evaluated in the order they are supplied.
+                                          they are evaluated in the order they are supplied. Append ":<version>" or ":<label>"
+                                          to read that version of each parameter instead of the latest, like /ep/conf:release-42.
+                                          Parameters without a matching version are left out.
+           --lock                       : specify a lock file pinning individual parameters, with one "name:<version>" or
+                                          "name:<label>" per line, like /ep/conf/ecs/host:3. Pins take precedence over -s selectors.
       -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
       -f | --filename                   : specify a configuration filename. this is resolved as a path relative to the -C confDir, and the basename of
                                           the filename (filename minus last extension) is treated as a suffix appended to each SSM param path prefix in turn.
+                                          Append ":<format>" to read and write the file in a format other than its extension suggests, like
+                                          -f application.conf:yaml. The format does not affect the SSM param path.
+                                          Specify -f - with a format and a --param-suffix to write to stdout on get, or read from stdin otherwise.
+           --param-suffix               : the filename standing in for -f - when building SSM param paths, like myapp.
+           --format                     : the format of every -f file without a ":<format>" suffix, like yaml, json, toml, ini, env, sh,
+                                          xml, k8s.yaml or properties. Defaults to the format matching each file's extension.
+      -R | --recursive                  : include parameters in sub-paths below each file's parameter path. Their keys are the remainder of
+                                          the parameter name, with sub-path levels joined by the --key-separator.
+           --key-separator              : the separator joining sub-path levels in the keys of properties files. Defaults to "/". Specify
+                                          "." to join them like dotted property names. Nested JSON and YAML objects map onto sub-paths.
            --no-get-secure-string       : if a parameter is of type SecureString, it will not be saved to the file.
            --get-key-id                 : if a parameter is of type SecureString, save its associated KMS keyId/alias to the file with the parameter 
                                           name suffixed with "_SecureStringKeyId".
+           --shell-keys                 : how keys are turned into variable names in .sh files. "underscore" (default) replaces invalid characters
+                                          with "_", "upper" also converts to upper case, and "strict" fails on invalid keys. Renamed variables
+                                          are annotated with their original key, so that the file can be put back.
+           --merge                      : how get merges the existing local file with SSM. "overlay" (default) overwrites local values with
+                                          SSM values and keeps the other local keys, "replace" writes only the SSM values, and
+                                          "preserve-local" adds SSM keys while keeping local values. Stale local keys are reported.
+           --file-mode                  : the octal permissions of files written on get, like 0640. Defaults to 0600 when the file holds any
+                                          SecureString value, and otherwise to the permissions of the existing file, or 0644.
+           --file-owner                 : the user name or uid to own files written on get.
+           --file-group                 : the group name or gid to own files written on get.
+           --backup                     : keep the previous version of each file written on get, with a .bak extension.
+           --comment-removed            : when saving an existing .properties file, comment out the lines of keys that were removed instead
+                                          of dropping them. Comments, blank lines and key order are always kept, and new keys are appended.
+           --k8s-name                   : the metadata.name of the ConfigMap and Secret written to .k8s.yaml files. Defaults to the
+                                          filename minus its extension, like "my-app" for my_app.k8s.yaml.
+           --k8s-namespace              : the metadata.namespace of the ConfigMap and Secret written to .k8s.yaml files.
+           --toml-infer-types           : write new values to .toml files as integers, floats, booleans or datetimes where their string form
+                                          allows, instead of as strings. Values keep their type from the existing file either way.
+           --xml-comment                : the <comment> of .xml files written on get. Defaults to the comment of the existing file, if any.
 
     EXAMPLES
 
@@ -101,6 +159,23 @@ OPERATION
 
          Get SSM parameters named /ep/conf/ecs/* and /ep/conf/prod/ecs/*, and /ep/conf/tomcat/* and /ep/conf/prod/tomcat/*, and store them at paths 
          /root/ep/conf/ecs.properties and /root/ep/conf/tomcat.properties, respectively.
+
+      4. Nested documents
+
+           %[1]s get -R -s /ep/conf -C /root/ep/conf -f application.yml
+
+         Get SSM parameters named /ep/conf/application/*, including those in deeper sub-paths like /ep/conf/application/spring/datasource/url,
+         and store them as nested objects in /root/ep/conf/application.yml. With -R, nested JSON and YAML objects are put to the same
+         sub-paths. Without -R, only the top level of a nested document is read, and put refuses nested keys.
+         StringList parameters are stored as arrays. In properties files, they are stored as comma-separated values with a buddy
+         property suffixed with "_StringList", so that put writes them back as StringLists.
+
+      5. Labeled versions
+
+           %[1]s get -s /ep/conf:release-42 -s /ep/conf/prod:release-42 -C /root/ep/conf -f ecs.properties
+
+         Get the versions of SSM parameters named /ep/conf/ecs/* and /ep/conf/prod/ecs/* that were labeled release-42 by the label
+         operation, and store them at path /root/ep/conf/ecs.properties.
 `, argv0)
 }
 
@@ -114,7 +189,7 @@ OPERATION
     USAGE
 
       %[1]s put [ --no-put-secure-string ] [ --overwrite-put | --clear-on-put ] [ --key-id-put-all <keyId|keyAlias> ]
-            -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+            [ -R [ --key-separator <sep> ] ] -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
 
     OPTIONS
 
@@ -128,6 +203,10 @@ OPERATION
                                           overwrite any existing values in that situation.
            --clear-on-put               : convenience flag to first delete all parameters at the specified parameter path prefix.
            --no-put-secure-string       : if a property has a buddy _SecureStringKeyId property, it will not be uploaded to SSM.
+      -R | --recursive                  : put keys containing the --key-separator, and the nested keys of JSON, YAML and TOML files, to
+                                          parameter sub-paths. --clear-on-put then also deletes the parameters in sub-paths.
+           --key-separator              : with -R, keys containing this separator are put to parameter sub-paths. Defaults to "/". Without -R,
+                                          keys are put as they are, so that only "/" reaches a sub-path.
 
     EXAMPLES
 
@@ -147,6 +226,43 @@ OPERATION
 `, argv0)
 }
 
+func helpSync() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  sync                                  : Make the parameters at a single path prefix exactly match one or more
+                                          specified filenames, putting only new or changed values and deleting
+                                          only parameters whose names are missing from the file.
+
+    USAGE
+
+      %[1]s sync [ --no-put-secure-string ] [ --force ] [ --key-id-put-all <keyId|keyAlias> ]
+            -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix.
+      -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
+      -f | --filename                   : specify a configuration filename. this is resolved as a path relative to the -C confDir, and the basename of
+                                          the filename (filename minus last extension) is treated as a suffix appended to each SSM param path prefix in turn.
+      -k | --key-id-put-all             : specify a KMS key ID or key alias to use to encrypt all uploaded parameters as SecureStrings.
+           --no-put-secure-string       : if a property has a buddy _SecureStringKeyId property, it will not be uploaded to SSM. SecureString parameters
+                                          are not deleted from SSM.
+           --force                      : normally, the command will fail if a property without a buddy _SecureStringKeyId property would replace a
+                                          SecureString parameter with a String. use this flag to replace it anyway.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s sync -s /ep/conf -C /root/ep/conf -f ecs.properties
+
+         Read values from /root/ep/conf/ecs.properties, create or update SSM parameters at path prefix /ep/conf/ecs whose values, types or
+         KMS keys differ from the file, and delete SSM parameters at path prefix /ep/conf/ecs that are not present in the file. Unchanged
+         parameters keep their current version.
+`, argv0)
+}
+
 func helpDelete() string {
 	return fmt.Sprintf(`
 OPERATION
@@ -156,7 +272,7 @@ OPERATION
 
     USAGE
 
-      %[1]s delete -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ] 
+      %[1]s delete [ -R [ --key-separator <sep> ] ] -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
 
     OPTIONS
 
@@ -165,6 +281,10 @@ OPERATION
       -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
       -f | --filename                   : specify a configuration filename. this is resolved as a path relative to the -C confDir, and the basename of 
                                           the filename (filename minus last extension) is treated as a suffix appended to each SSM param path prefix in turn.
+      -R | --recursive                  : include parameters in sub-paths below each file's parameter path. Their keys are the remainder of
+                                          the parameter name, with sub-path levels joined by the --key-separator.
+           --key-separator              : the separator joining sub-path levels in the keys of properties files. Defaults to "/". Specify
+                                          "." to join them like dotted property names. Nested JSON and YAML objects map onto sub-paths.
 
     EXAMPLES
 
@@ -192,12 +312,13 @@ OPERATION
 
     USAGE
 
-      %[1]s clear -s <prefix>
+      %[1]s clear [ -R ] -s <prefix>
 
     OPTIONS
 
       -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
                                           they are evaluated in the order they are supplied.
+      -R | --recursive                  : also delete parameters in sub-paths below each file's parameter path.
 
     EXAMPLES
 
@@ -210,6 +331,332 @@ OPERATION
 `, argv0)
 }
 
+func helpDiff() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  diff                                  : Compare each specified file against the SSM parameter values that get would
+                                          merge for it, and print added, removed and changed keys. Like diff(1), exits
+                                          with status 0 when there are no differences, 1 when there are differences, and
+                                          2 when the comparison fails.
+
+    USAGE
+
+      %[1]s diff [ --show-secrets ] [ --no-get-secure-string ] [ --get-key-id ] -s <prefix> [ [ -s <prefix> ] ... ]
+            [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
+                                          they are evaluated in the order they are supplied. Append ":<version>" or ":<label>"
+                                          to read that version of each parameter instead of the latest, like /ep/conf:release-42.
+                                          Parameters without a matching version are left out.
+           --lock                       : specify a lock file pinning individual parameters, with one "name:<version>" or
+                                          "name:<label>" per line, like /ep/conf/ecs/host:3. Pins take precedence over -s selectors.
+      -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
+      -f | --filename                   : specify a configuration filename. this is resolved as a path relative to the -C confDir, and the basename of
+                                          the filename (filename minus last extension) is treated as a suffix appended to each SSM param path prefix in turn.
+           --show-secrets               : print SecureString values instead of masking them.
+           --no-get-secure-string       : exclude SecureString parameters from the comparison.
+           --get-key-id                 : also compare the "_SecureStringKeyId" sidecar properties.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s diff -s /ep/conf -s /ep/conf/prod -C /root/ep/conf -f ecs.properties
+
+         Merge SSM parameters named /ep/conf/ecs/* and /ep/conf/prod/ecs/*, and compare them with the keys in /root/ep/conf/ecs.properties.
+         Keys prefixed with "+" are only in the file, keys prefixed with "-" are only in SSM, and keys prefixed with "~" have changed.
+`, argv0)
+}
+
+func helpExec() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  exec                                  : Run a command with the SSM parameter values that get would merge for each
+                                          specified filename injected as environment variables, without writing them
+                                          to disk. Signals are forwarded to the command, and its exit code is returned.
+
+    USAGE
+
+      %[1]s exec [ --env-keys <mode> ] [ --no-get-secure-string ] [ -R [ --key-separator <sep> ] ] -s <prefix> [ [ -s <prefix> ] ... ]
+            -f filename [ [ -f filename ] ... ] -- command [ args ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
+                                          they are evaluated in the order they are supplied. Append ":<version>" or ":<label>"
+                                          to read that version of each parameter instead of the latest, like /ep/conf:release-42.
+                                          Parameters without a matching version are left out.
+           --lock                       : specify a lock file pinning individual parameters, with one "name:<version>" or
+                                          "name:<label>" per line, like /ep/conf/ecs/host:3. Pins take precedence over -s selectors.
+      -f | --filename                   : specify a filename, whose basename (filename minus last extension) is treated as a suffix appended to
+                                          each SSM param path prefix in turn. The file itself is not read or written. When more than one -f
+                                          argument is specified, parameters for later filenames override those for earlier ones.
+      -R | --recursive                  : include parameters in sub-paths below each file's parameter path.
+           --env-keys                   : how keys are turned into environment variable names. "upper" (default) replaces invalid characters
+                                          with "_" and converts to upper case, "underscore" only replaces invalid characters, and "strict" fails
+                                          on invalid keys.
+           --no-get-secure-string       : if a parameter is of type SecureString, it will not be injected.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s exec -s /ep/conf -s /ep/conf/prod -f app -- java -jar app.jar
+
+         Merge SSM parameters named /ep/conf/app/* and /ep/conf/prod/app/*, and run java with each parameter as an environment
+         variable, like DB_URL for /ep/conf/app/db.url, in addition to the environment of %[1]s.
+`, argv0)
+}
+
+func helpRender() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  render                                : Render a Go text/template for each specified filename with the SSM parameter
+                                          values that get would merge for it, and write the output to the file.
+
+    USAGE
+
+      %[1]s render [ --no-get-secure-string ] [ -R [ --key-separator <sep> ] ] [ --file-mode <mode> ] [ --backup ]
+            -s <prefix> [ [ -s <prefix> ] ... ] [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
+                                          they are evaluated in the order they are supplied. Append ":<version>" or ":<label>"
+                                          to read that version of each parameter instead of the latest, like /ep/conf:release-42.
+                                          Parameters without a matching version are left out.
+           --lock                       : specify a lock file pinning individual parameters, with one "name:<version>" or
+                                          "name:<label>" per line, like /ep/conf/ecs/host:3. Pins take precedence over -s selectors.
+      -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
+      -f | --filename                   : specify an output filename. this is resolved as a path relative to the -C confDir, and the basename of
+                                          the filename (filename minus last extension) is treated as a suffix appended to each SSM param path prefix
+                                          in turn. The template is read from the same path with a .tmpl extension appended.
+      -R | --recursive                  : include parameters in sub-paths below each file's parameter path.
+           --no-get-secure-string       : if a parameter is of type SecureString, it will not be available to the template.
+           --file-mode, --file-owner,   : like get, the output is written atomically, and defaults to mode 0600 when any SecureString value
+           --file-group, --backup         is available to the template.
+
+    TEMPLATE FUNCTIONS
+
+           param KEY                    : the value of KEY, or "" if it is missing. Keys without dots may also be read like {{ .host }}.
+           required KEY                 : the value of KEY, failing the render if it is missing.
+           default FALLBACK VALUE       : VALUE, or FALLBACK if VALUE is empty, like {{ param "port" | default "8080" }}.
+           base64 VALUE                 : VALUE encoded as standard base64.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s render -s /ep/conf -s /ep/conf/prod -C /etc/nginx -f nginx.conf
+
+         Merge SSM parameters named /ep/conf/nginx/* and /ep/conf/prod/nginx/*, render the template /etc/nginx/nginx.conf.tmpl with them,
+         and write the output to /etc/nginx/nginx.conf.
+`, argv0)
+}
+
+func helpExport() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  export                                : Write every SSM parameter below a path prefix, recursively, to a versioned JSON or
+                                          YAML bundle, with its type, KMS key alias, description, tier, tags and value.
+
+    USAGE
+
+      %[1]s export -s <prefix> [ -C <confDir> ] -f bundle
+
+    OPTIONS
+
+      -s | --starts-with                : specify the SSM parameter path prefix to export.
+      -C | --conf-dir                   : specify a base configuration directory, against which the bundle filename is resolved relatively.
+      -f | --filename                   : specify the bundle filename, with a .json, .yaml or .yml extension, or a :json or :yaml format suffix.
+                                          Specify -f - with a format to write the bundle to stdout. Like get, the bundle is written atomically,
+                                          with mode 0600 by default when it holds any SecureString value.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s export -s /ep/conf -f ep-conf.yaml
+
+         Write every SSM parameter named /ep/conf/**, including SecureString values, to ep-conf.yaml.
+`, argv0)
+}
+
+func helpImport() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  import                                : Put every parameter of one or more bundles written by export below a path prefix.
+
+    USAGE
+
+      %[1]s import [ --on-conflict fail|skip|overwrite ] -s <prefix> [ -C <confDir> ] -f bundle [ [ -f bundle ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify the SSM parameter path prefix to import below, which may differ from the exported prefix.
+      -C | --conf-dir                   : specify a base configuration directory, against which bundle filenames are resolved relatively.
+      -f | --filename                   : specify a bundle filename, with a .json, .yaml or .yml extension, or a :json or :yaml format suffix.
+                                          Specify -f - with a format to read the bundle from stdin.
+           --on-conflict                : how to handle parameters that already exist. "fail" (default) puts nothing if any exists, "skip"
+                                          keeps the existing parameters, and "overwrite" puts a new version of them.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s --profile other import -s /ep/conf -f ep-conf.yaml
+
+         Create SSM parameters named /ep/conf/** in the account of the "other" profile, from the bundle ep-conf.yaml.
+`, argv0)
+}
+
+func helpCopy() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  copy | promote                        : Copy SSM parameters from one path prefix to another, for each specified filename or for
+                                          the whole subtree, keeping the type, KMS key, description, tier and tags of each.
+
+    USAGE
+
+      %[1]s copy [ --reencrypt-key <keyId|keyAlias> ] [ --on-conflict fail|skip|overwrite ] [ -R ] --from <prefix> --to <prefix>
+            [ -f filename [ [ -f filename ] ... ] ]
+
+    OPTIONS
+
+           --from                       : specify the SSM parameter path prefix to copy from.
+           --to                         : specify the SSM parameter path prefix to copy to.
+      -f | --filename                   : specify a filename, whose basename (filename minus last extension) is treated as a suffix appended to
+                                          both prefixes. The file itself is not read. Without -f, every parameter below --from is copied, recursively.
+      -R | --recursive                  : with -f, include parameters in sub-paths below each file's parameter path.
+           --reencrypt-key              : specify a KMS key ID or key alias to encrypt copied SecureStrings with, instead of their current key.
+           --on-conflict                : how to handle target parameters that already exist. "fail" (default) copies nothing if any exists,
+                                          "skip" keeps the existing parameters, and "overwrite" puts a new version of them.
+
+    EXAMPLES
+
+      1. Promote a file
+
+           %[1]s promote --from /ep/conf/preprod --to /ep/conf/prod --reencrypt-key prod-config --on-conflict overwrite -f ecs.properties
+
+         Copy SSM parameters named /ep/conf/preprod/ecs/* to /ep/conf/prod/ecs/*, encrypting SecureStrings with the KMS key aliased
+         alias/prod-config, and overwriting existing parameters.
+`, argv0)
+}
+
+func helpHistory() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  history                               : Print every version of the SSM parameter for each key of each specified filename, with its
+                                          modified date, modifying user, labels and value. Keys without a parameter are listed with no versions.
+
+    USAGE
+
+      %[1]s history [ --show-secrets ] [ -R ] -s <prefix> [ [ -s <prefix> ] ... ] [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified,
+                                          the history below each is printed in the order they are supplied.
+      -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
+      -f | --filename                   : specify a configuration filename, whose keys name the parameters. the basename of the filename
+                                          (filename minus last extension) is treated as a suffix appended to each SSM param path prefix in turn.
+      -R | --recursive                  : map keys with sub-path levels onto parameters in sub-paths below each file's parameter path.
+           --show-secrets               : print SecureString values instead of masking them.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s history -s /ep/conf -f ecs.properties
+
+         Print every version of the SSM parameter /ep/conf/ecs/<key> for each key in ecs.properties, oldest first.
+`, argv0)
+}
+
+func helpRollback() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  rollback                              : Put the values of an earlier version of the SSM parameter for each key of each specified
+                                          filename back as new versions.
+
+    USAGE
+
+      %[1]s rollback ( --to-version <number> | --to-time <time> ) [ -R ] -s <prefix> [ -C <confDir> ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix.
+      -C | --conf-dir                   : specify a base configuration directory, against which filenames are resolved relatively. Defaults to $CWD.
+      -f | --filename                   : specify a configuration filename, whose keys name the parameters. the basename of the filename
+                                          (filename minus last extension) is treated as a suffix appended to the SSM param path prefix.
+      -R | --recursive                  : map keys with sub-path levels onto parameters in sub-paths below each file's parameter path.
+           --to-version                 : roll each parameter back to the value of this version number. Parameters without it are skipped.
+           --to-time                    : roll each parameter back to the value of its latest version at this RFC 3339 time, like
+                                          2018-06-01T12:00:00Z. Parameters created after it are skipped.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s rollback --to-time 2018-06-01T12:00:00Z -s /ep/conf/prod -f ecs.properties
+
+         Put the value that the SSM parameter /ep/conf/prod/ecs/<key> for each key in ecs.properties had at noon UTC on June 1st
+         as a new version. Parameters whose value has not changed since are left alone.
+`, argv0)
+}
+
+func helpLabel() string {
+	return fmt.Sprintf(`
+OPERATION
+
+  label                                 : Attach one or more labels to the latest version of each SSM parameter that get would
+                                          read for each specified filename, so that get can read exactly that set later.
+
+    USAGE
+
+      %[1]s label --label <label> [ [ --label <label> ] ... ] [ -R ] [ --lock <file> ]
+            -s <prefix>[:<selector>] [ [ -s <prefix>[:<selector>] ] ... ] -f filename [ [ -f filename ] ... ]
+
+    OPTIONS
+
+      -s | --starts-with                : specify an SSM parameter path prefix. When more than one -s argument is specified, the
+                                          parameters below each are labeled. Append ":<version>" or ":<label>" to label that version of
+                                          each parameter instead of the latest.
+           --lock                       : specify a lock file pinning the versions of individual parameters to label, like get.
+      -f | --filename                   : specify a filename, whose basename (filename minus last extension) is treated as a suffix appended
+                                          to each SSM param path prefix in turn. The file itself is not read.
+      -R | --recursive                  : include parameters in sub-paths below each file's parameter path.
+           --label                      : the label to attach. A label is moved off any other version of the same parameter.
+                                          Labels may contain letters, numbers, periods, hyphens and underscores, and may not begin
+                                          with a number, aws or ssm.
+
+    EXAMPLES
+
+      1. Simplest Case
+
+           %[1]s label --label release-42 -s /ep/conf -s /ep/conf/prod -f ecs.properties
+
+         Label the latest version of each SSM parameter named /ep/conf/ecs/* and /ep/conf/prod/ecs/* with release-42, to be read
+         later by get -s /ep/conf:release-42 -s /ep/conf/prod:release-42 -f ecs.properties.
+
+      2. Promote a labeled set
+
+           %[1]s label --label production -s /ep/conf:release-42 -f ecs.properties
+
+         Move the production label onto the versions of each SSM parameter named /ep/conf/ecs/* labeled release-42.
+`, argv0)
+}
+
 func helpOperations() string {
 	return fmt.Sprintf(`
   Specify %[1]s -h <operation> to see detailed help for one of the following operations.
@@ -223,9 +670,36 @@ OPERATIONS
   put                                   : Upload new parameter values to a single path prefix, from one or
                                           more specified filenames.
 
+  sync                                  : Make the parameters at a single path prefix exactly match one or more
+                                          specified filenames, leaving unchanged parameters alone.
+
   delete                                : Delete SSM parameters within a particular path prefix according to
                                           parameter names present in one or more specified filenames.
 
   clear                                 : Delete ALL SSM parameters within in the specified path prefix.
+
+  diff                                  : Compare each specified file against the SSM parameter values that get would
+                                          merge for it, and print added, removed and changed keys.
+
+  exec                                  : Run a command with the SSM parameter values that get would merge for each
+                                          specified filename injected as environment variables.
+
+  render                                : Render a Go text/template for each specified filename with the SSM parameter
+                                          values that get would merge for it, and write the output to the file.
+
+  export                                : Write every SSM parameter below a path prefix, recursively, to a versioned JSON or
+                                          YAML bundle.
+
+  import                                : Put every parameter of one or more bundles written by export below a path prefix.
+
+  copy | promote                        : Copy SSM parameters from one path prefix to another, keeping their KMS keys or
+                                          re-encrypting SecureStrings with another key.
+
+  history                               : Print every version of each SSM parameter that get would read for each specified filename.
+
+  rollback                              : Put the values of an earlier version of each SSM parameter back as new versions.
+
+  label                                 : Attach labels to the latest version of each SSM parameter that get would read for each
+                                          specified filename.
 `, argv0)
 }
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 Mark Adamcin
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+const xmlPropsHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE properties SYSTEM "http://java.sun.com/dtd/properties.dtd">
+`
+
+// the XmlPropsSerial reads and writes the java.util.Properties XML format, as
+// read by Properties.loadFromXML. The <comment> of an existing file is kept
+// when saving over it, unless a Comment is given.
+type XmlPropsSerial struct {
+	// the <comment> to write, or empty to keep the comment of the existing
+	// file
+	Comment string
+}
+
+type xmlProps struct {
+	XMLName xml.Name        `xml:"properties"`
+	Comment *string         `xml:"comment,omitempty"`
+	Entries []xmlPropsEntry `xml:"entry"`
+}
+
+type xmlPropsEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func readXmlProps(r io.Reader) (*xmlProps, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := xmlProps{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (s XmlPropsSerial) Load(r io.Reader) (map[string]string, error) {
+	doc, err := readXmlProps(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]string, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		dict[entry.Key] = entry.Value
+	}
+	return dict, nil
+}
+
+func (s XmlPropsSerial) Save(w io.Writer, dict *map[string]string) error {
+	return s.SaveOver(w, nil, dict)
+}
+
+func (s XmlPropsSerial) SaveOver(w io.Writer, previous io.Reader, dict *map[string]string) error {
+	doc := xmlProps{}
+	if len(s.Comment) > 0 {
+		comment := s.Comment
+		doc.Comment = &comment
+	} else if previous != nil {
+		if existing, err := readXmlProps(previous); err == nil {
+			doc.Comment = existing.Comment
+		}
+	}
+
+	keys := make([]string, 0, len(*dict))
+	for key := range *dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		doc.Entries = append(doc.Entries, xmlPropsEntry{Key: key, Value: (*dict)[key]})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xmlPropsHeader); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (s XmlPropsSerial) Configure(name string, options SerialOptions) Serial {
+	return XmlPropsSerial{Comment: options.XmlComment}
+}
+
+func init() {
+	RegisterSerial(XmlPropsSerial{}, ".xml")
+}
@@ -17,50 +17,32 @@
 package main
 
 import (
-	"errors"
-	"fmt"
 	"gopkg.in/yaml.v2"
-	"os"
+	"io"
 )
 
 type YamlSerial struct{}
 
-func (s YamlSerial) Load(path string) (map[string]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	dec := yaml.NewDecoder(file)
+func (s YamlSerial) Load(r io.Reader) (map[string]string, error) {
+	dec := yaml.NewDecoder(r)
 	var m map[string]interface{}
 	if err := dec.Decode(&m); err != nil {
 		return nil, err
 	}
 
-	dict := make(map[string]string)
-	for k, v := range m {
-		switch v.(type) {
-		case string:
-			dict[k] = v.(string)
-		case []interface{}, map[string]interface{}:
-			return nil, errors.New("nested arrays and objects are not supported. json key " + k)
-		default:
-			dict[k] = fmt.Sprintf("%v", v)
-		}
-	}
-
-	return dict, nil
+	return flattenDoc(m)
 }
 
-func (s YamlSerial) Save(path string, dict *map[string]string) error {
-	file, err := os.Create(path)
+func (s YamlSerial) Save(w io.Writer, dict *map[string]string) error {
+	doc := nestDict(*dict)
 
-	if err != nil {
-		return err
-	}
+	enc := yaml.NewEncoder(w)
+	return enc.Encode(doc)
+}
 
-	enc := yaml.NewEncoder(file)
-	return enc.Encode(*dict)
+// nested objects are flattened into keys joined by NestedKeySeparator.
+func (s YamlSerial) IsNested() bool {
+	return true
 }
 
 func init() {